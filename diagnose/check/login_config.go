@@ -0,0 +1,44 @@
+package check
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/xackery/overseer/pkg/config"
+	"github.com/xackery/overseer/pkg/message"
+)
+
+// LoginConfig checks login.json when overseer runs the loginserver
+func LoginConfig(cfg *config.OverseerConfiguration, emuCfg *config.EQEmuConfiguration) error {
+	if !emuCfg.WebAdmin.Launcher.RunLoginServer && cfg.AppConfig("loginserver") == nil {
+		return nil
+	}
+	if !cfg.IsEnabled("loginserver") {
+		return nil
+	}
+
+	fi, err := os.Stat(cfg.ServerPath + "/login.json")
+	if err != nil {
+		return fmt.Errorf("not found")
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("is a directory")
+	}
+
+	login, err := config.LoadLoginConfig(cfg.ServerPath + "/login.json")
+	if err != nil {
+		return fmt.Errorf("load: %w", err)
+	}
+
+	err = login.Verify()
+	if err != nil {
+		return err
+	}
+	host := emuCfg.Server.World.LoginServer1.Host
+	if host != "" && host != "127.0.0.1" && host != "localhost" {
+		message.Badf("eqemu_config.json world.loginserver1.host is %s, but the loginserver runs locally", host)
+	}
+
+	message.OK("Login Config OK")
+	return nil
+}
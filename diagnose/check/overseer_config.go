@@ -55,6 +55,15 @@ func OverseerConfig() error {
 					message.Badf("parse zone_count value %s: %s", value, err)
 				}
 				tmpConfig.ZoneCount = 1
+			case "min_zones", "max_zones", "idle_zones":
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					message.Badf("parse %s value %s: %s", key, value, err)
+				}
+				if key == "max_zones" {
+					cfg.MaxZones = n
+					tmpConfig.ZoneCount = 1
+				}
 			case "setup":
 				cfg.Setup = strings.ToLower(value)
 				tmpConfig.Setup = "1"
@@ -103,7 +112,7 @@ func OverseerConfig() error {
 	}
 
 	if tmpConfig.ZoneCount == 0 {
-		message.Bad("overseer.ini missing zone_count")
+		message.Bad("overseer.ini missing zone_count or max_zones")
 	}
 
 	if tmpConfig.Setup == "" {
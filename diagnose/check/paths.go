@@ -39,7 +39,7 @@ func Paths(cfg *config.OverseerConfiguration, emuCfg *config.EQEmuConfiguration)
 
 		}
 	}
-	if cfg.ZoneCount == 0 {
+	if cfg.ZoneCount == 0 && cfg.MaxZones == 0 {
 		message.Badf("overseer.ini zone_count is 0 ")
 		message.Link("https://o.eqcodex.com/102")
 
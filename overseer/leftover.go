@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/erikgeiser/promptkit/selection"
+	"github.com/xackery/overseer/pkg/config"
+	"github.com/xackery/overseer/pkg/flog"
+	"github.com/xackery/overseer/pkg/message"
+	"github.com/xackery/overseer/pkg/pidfile"
+	"github.com/xackery/overseer/pkg/runner"
+)
+
+// leftovers checks for apps a previous overseer left running, and adopts, reaps or ignores them
+// based on leftover_action. Returns the pid of each app to adopt, keyed by name, and the apps
+// left alone, which must be kept in the new pidfile
+func leftovers(cfg *config.OverseerConfiguration) (map[string]int, []pidfile.Entry, error) {
+	entries, err := pidfile.Leftovers(pidfile.DefaultPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("leftovers: %w", err)
+	}
+
+	apps := []pidfile.Entry{}
+	names := []string{}
+	for _, entry := range entries {
+		if entry.Name == pidfile.OverseerName {
+			return nil, nil, fmt.Errorf("overseer is already running as pid %d", entry.PID)
+		}
+		apps = append(apps, entry)
+		names = append(names, fmt.Sprintf("%s (pid %d)", entry.Name, entry.PID))
+	}
+	if len(apps) == 0 {
+		return nil, nil, nil
+	}
+	flog.Printf("[leftover] found %s\n", strings.Join(names, ", "))
+
+	action := cfg.LeftoverAction
+	if cfg.Setup == "docker" {
+		// containers can't adopt a host process
+		action = config.LeftoverReap
+	}
+	if action == config.LeftoverAsk {
+		fmt.Printf("A previous overseer left %d apps running: %s\n", len(apps), strings.Join(names, ", "))
+		action, err = selection.New("What should be done with them?", []string{config.LeftoverAdopt, config.LeftoverReap, config.LeftoverIgnore}).RunPrompt()
+		if err != nil {
+			return nil, nil, fmt.Errorf("leftover prompt: %w", err)
+		}
+	}
+
+	adopt := make(map[string]int)
+	ignored := []pidfile.Entry{}
+	switch action {
+	case config.LeftoverAdopt:
+		for _, entry := range apps {
+			// an app ignored by an earlier run can share a name with one started after it
+			_, ok := adopt[entry.Name]
+			if ok {
+				flog.Printf("[leftover] %s pid %d has the same name as an adopted app, leaving it alone\n", entry.Name, entry.PID)
+				ignored = append(ignored, entry)
+				continue
+			}
+			adopt[entry.Name] = entry.PID
+		}
+		message.OKf("Adopting %d apps left running\n", len(adopt))
+	case config.LeftoverReap:
+		for _, entry := range apps {
+			err = runner.Reap(entry.Name, entry.PID, cfg.StopGraceFor(entry.Name))
+			if err != nil {
+				return nil, nil, fmt.Errorf("reap %s: %w", entry.Name, err)
+			}
+		}
+		message.OKf("Stopped %d apps left running\n", len(apps))
+	default:
+		message.Skipf("Leaving %d apps left running alone\n", len(apps))
+		ignored = apps
+	}
+	flog.Printf("[leftover] action: %s\n", action)
+	return adopt, ignored, nil
+}
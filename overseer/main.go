@@ -10,11 +10,15 @@ import (
 	"time"
 
 	"github.com/xackery/overseer/pkg/config"
+	"github.com/xackery/overseer/pkg/control"
 	"github.com/xackery/overseer/pkg/flog"
 	"github.com/xackery/overseer/pkg/gui"
 	"github.com/xackery/overseer/pkg/message"
+	"github.com/xackery/overseer/pkg/metrics"
 	"github.com/xackery/overseer/pkg/operation"
+	"github.com/xackery/overseer/pkg/pidfile"
 	"github.com/xackery/overseer/pkg/signal"
+	"github.com/xackery/overseer/pkg/telnet"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/xackery/overseer/pkg/dashboard"
@@ -57,28 +61,53 @@ func run() error {
 		return fmt.Errorf("load overseer config: %w", err)
 	}
 
-	err = flog.New("overseer.log")
+	err = flog.New("overseer.log", config.Log.Rotation)
 	if err != nil {
 		return fmt.Errorf("new flog: %w", err)
 	}
 	defer flog.Close()
 
-	err = parseManager(config)
+	emuCfg := loadEQEmuConfig(config)
+
+	adopt, ignored, err := leftovers(config)
+	if err != nil {
+		return err
+	}
+
+	err = pidfile.New(pidfile.DefaultPath, ignored...)
+	if err != nil {
+		return fmt.Errorf("pidfile: %w", err)
+	}
+	defer pidfile.Close()
+
+	if config.ControlAddress != "" {
+		err = control.New(config.ControlAddress, config.ControlToken)
+		if err != nil {
+			return fmt.Errorf("control api: %w", err)
+		}
+		defer control.Close()
+	}
+
+	err = parseManager(config, emuCfg, adopt)
 	if err != nil {
 		return fmt.Errorf("initialize manager: %w", err)
 	}
+	metrics.New()
+	telnet.New(config.DisplayNameFor("world"))
 
 	if runtime.GOOS == "windows" {
 		return runWindows(ctx, g)
 	}
 	time.Sleep(10 * time.Millisecond)
 	p := tea.NewProgram(dashboard.New(Version))
+	done := make(chan struct{})
+	// apps keep reporting while they shut down, so updates are drained until the dashboard exits
 	go func() {
 		for {
 			select {
 			case <-reporter.SendUpdateChan:
 				p.Send(dashboard.RefreshRequest{})
-			case <-signal.Ctx().Done():
+			case <-done:
 				return
 			case <-time.After(5 * time.Second):
 				p.Send(dashboard.RefreshRequest{})
@@ -87,6 +116,7 @@ func run() error {
 	}()
 
 	_, err = p.Run()
+	close(done)
 	if err != nil {
 		return err
 	}
@@ -94,13 +124,27 @@ func run() error {
 	return nil
 }
 
-func parseManager(cfg *config.OverseerConfiguration) error {
+// loadEQEmuConfig loads eqemu_config.json and sets the static zones of cfg, so leftovers and
+// managed apps both find their [zone] settings. Returns nil if it can't be loaded
+func loadEQEmuConfig(cfg *config.OverseerConfiguration) *config.EQEmuConfiguration {
+	emuCfg, err := config.LoadEQEmuConfig(cfg.ServerPath + "/eqemu_config.json")
+	if err != nil {
+		flog.Printf("[overseer] load eqemu_config.json, skipping launcher and telnet settings: %s\n", err)
+		return nil
+	}
+	cfg.StaticZones = emuCfg.WebAdmin.Launcher.StaticZoneNames()
+	return emuCfg
+}
+
+func parseManager(cfg *config.OverseerConfiguration, emuCfg *config.EQEmuConfiguration, adopt map[string]int) error {
 	var err error
 	winExt := ".exe"
 	if runtime.GOOS != "windows" {
 		winExt = ""
 	}
 
+	manager.ShutdownTimeout = cfg.ShutdownTimeout
+
 	setupType := manager.SetupDefault
 	switch cfg.Setup {
 	case "docker":
@@ -154,40 +198,199 @@ func parseManager(cfg *config.OverseerConfiguration) error {
 		return fmt.Errorf("abs exePath: %w", err)
 	}
 
-	err = manager.Manage(setupType, "world", cfg.IsOverseerVerboseLog, wdPath, exePath, "world"+winExt)
-	if err != nil {
-		return fmt.Errorf("manage world: %w", err)
+	// zones and ucs wait on world by default, custom apps can set depends_on in their [name] section.
+	// name is the section an app is configured by, and what it is shown as unless display_name is set
+	newSpec := func(name string, exeName string, dependsOn ...string) manager.AppSpec {
+		spec := manager.AppSpec{
+			DisplayName:  cfg.DisplayNameFor(name),
+			IsLogged:     cfg.IsOverseerVerboseLog,
+			WdPath:       wdPath,
+			ExePath:      exePath,
+			ExeName:      exeName,
+			Policy:       cfg.RestartPolicyFor(name),
+			Watchdog:     cfg.WatchdogFor(name),
+			Recycle:      cfg.RecycleFor(name),
+			DependsOn:    cfg.DependsOnFor(name, dependsOn...),
+			ReadyTimeout: cfg.ReadyTimeoutFor(name),
+			Image:        cfg.DockerImage,
+			StopGrace:    cfg.StopGraceFor(name),
+			AdoptPID:     adopt[cfg.DisplayNameFor(name)],
+			Rules:        cfg.RulesFor(name),
+			LogDir:       cfg.Log.Dir,
+			LogRotation:  cfg.Log.Rotation,
+		}
+		app := cfg.AppConfig(name)
+		if app == nil {
+			return spec
+		}
+		spec.Args = app.Args
+		spec.Env = app.Env
+		spec.ReadyMatch = app.ReadyMatch
+		if app.WorkingDir != "" {
+			spec.WdPath = app.WorkingDir
+			if !filepath.IsAbs(spec.WdPath) {
+				spec.WdPath = filepath.Join(wdPath, app.WorkingDir)
+			}
+		}
+		return spec
+	}
+
+	// manage supervises an app, unless its section has enabled = 0
+	manage := func(name string, spec manager.AppSpec) error {
+		if !cfg.IsEnabled(name) {
+			flog.Printf("[overseer] %s is disabled, not starting\n", name)
+			return nil
+		}
+		_, err := manager.Manage(setupType, spec)
+		if err != nil {
+			return fmt.Errorf("manage %s: %w", spec.DisplayName, err)
+		}
+		return nil
 	}
-	time.Sleep(50 * time.Millisecond)
 
-	for i := 0; i < cfg.ZoneCount; i++ {
-		err = manager.Manage(setupType, fmt.Sprintf("zone%d", i), cfg.IsOverseerVerboseLog, wdPath, exePath, "zone"+winExt)
+	launcher := config.LauncherConfig{}
+	if emuCfg != nil {
+		launcher = emuCfg.WebAdmin.Launcher
+		client, err := telnet.NewClientFromConfig(emuCfg.Server.World.Telnet, cfg.TelnetUsername, cfg.TelnetPassword)
 		if err != nil {
-			return fmt.Errorf("manage zone%d: %w", i, err)
+			flog.Printf("[overseer] world telnet: %s, skipping stats and telnet checks\n", err)
+		} else {
+			telnet.SetClient(client)
 		}
-		time.Sleep(50 * time.Millisecond)
 	}
 
-	time.Sleep(50 * time.Millisecond)
+	// shared_memory can't be rebuilt while adopted apps are using it
+	if cfg.IsSharedMemoryPreflight && cfg.IsEnabled("shared_memory") && len(adopt) == 0 {
+		manager.AddPreflight(newSpec("shared_memory", "shared_memory"+winExt))
+		err = manager.RunPreflight(signal.Ctx())
+		if err != nil {
+			return fmt.Errorf("preflight: %w", err)
+		}
+	}
 
-	err = manager.Manage(setupType, "ucs", cfg.IsOverseerVerboseLog, wdPath, exePath, "ucs"+winExt)
+	// loginserver and queryserv run when eqemu_config.json's launcher asks for them, or when they have a [name] section
+	if launcher.RunLoginServer || cfg.AppConfig("loginserver") != nil {
+		if cfg.IsEnabled("loginserver") {
+			checkLoginConfig(cfg.ServerPath + "/login.json")
+		}
+		err = manage("loginserver", newSpec("loginserver", "loginserver"+winExt))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = manage("world", newSpec("world", "world"+winExt))
 	if err != nil {
-		return fmt.Errorf("manage ucs: %w", err)
+		return err
 	}
-	time.Sleep(50 * time.Millisecond)
-	//manager.Manage(setupType, "queryserv", wdPath, exePath, "queryserv"+winExt)
-	//time.Sleep(50 * time.Millisecond)
 
-	//manager.Manage(setupType, "loginserver", wdPath, exePath, "loginserver"+winExt)
-	//	time.Sleep(50 * time.Millisecond)
+	// static zones are named after the zone they serve, and always restarted as it
+	for _, zone := range launcher.StaticZoneNames() {
+		spec := newSpec(zone, "zone"+winExt, "world")
+		spec.Args = []string{zone}
+		spec.Zone = zone
+		err = manage(zone, spec)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.MaxZones > 0 && cfg.IsEnabled("zone") {
+		minZones := cfg.MinZones
+		if minZones == 0 {
+			minZones = launcher.MinZoneProcesses
+		}
+		err = manager.StartZonePool(manager.ZonePool{
+			Setup: setupType,
+			Min:   minZones,
+			Max:   cfg.MaxZones,
+			Idle:  cfg.IdleZones,
+			Spec: func(displayName string) manager.AppSpec {
+				return newSpec(displayName, "zone"+winExt, "world")
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("zone pool: %w", err)
+		}
+	} else if cfg.MaxZones == 0 {
+		for i := 0; i < cfg.ZoneCount; i++ {
+			name := fmt.Sprintf("zone%d", i)
+			err = manage(name, newSpec(name, "zone"+winExt, "world"))
+			if err != nil {
+				return err
+			}
+		}
+	}
 
+	err = manage("ucs", newSpec("ucs", "ucs"+winExt, "world"))
+	if err != nil {
+		return err
+	}
+	if launcher.RunQueryServ || cfg.AppConfig("queryserv") != nil {
+		err = manage("queryserv", newSpec("queryserv", "queryserv"+winExt, "world"))
+		if err != nil {
+			return err
+		}
+	}
+
+	isListed := make(map[string]bool)
 	for _, app := range cfg.Apps {
 		nonExt := strings.TrimSuffix(app, filepath.Ext(app))
-		err = manager.Manage(setupType, nonExt, cfg.IsOverseerVerboseLog, wdPath, exePath, app)
+		isListed[strings.ToLower(nonExt)] = true
+		err = manage(nonExt, newSpec(nonExt, app))
 		if err != nil {
-			return fmt.Errorf("manage %s: %w", nonExt, err)
+			return err
+		}
+	}
+
+	for _, app := range cfg.CustomApps() {
+		if isListed[app.Name] {
+			continue
+		}
+		spec := newSpec(app.Name, filepath.Base(app.Exe))
+		spec.ExePath = filepath.Dir(app.Exe)
+		if !filepath.IsAbs(spec.ExePath) {
+			spec.ExePath = filepath.Join(exePath, spec.ExePath)
+		}
+		err = manage(app.Name, spec)
+		if err != nil {
+			return err
+		}
+	}
+
+	schedule := cfg.RestartSchedule
+	if schedule.Cron != nil {
+		opts := manager.RestartOptions{IsSkipPreflight: !schedule.IsSharedMemory}
+		if len(schedule.Update) > 0 {
+			exeName := schedule.Update[0]
+			if filepath.Ext(exeName) == "" {
+				exeName += winExt
+			}
+			spec := newSpec("update", exeName)
+			spec.Args = schedule.Update[1:]
+			opts.Steps = append(opts.Steps, spec)
+		}
+		err = manager.StartRestartSchedule(manager.RestartSchedule{
+			Cron:     schedule.Cron,
+			Warnings: schedule.Warnings,
+			Options:  opts,
+		})
+		if err != nil {
+			return fmt.Errorf("restart schedule: %w", err)
 		}
-		time.Sleep(50 * time.Millisecond)
 	}
 	return nil
 }
+
+// checkLoginConfig logs why the loginserver is likely to fail, it is still started so its own log shows the cause
+func checkLoginConfig(path string) {
+	login, err := config.LoadLoginConfig(path)
+	if err != nil {
+		flog.Printf("[overseer] loginserver login.json: %s\n", err)
+		return
+	}
+	err = login.Verify()
+	if err != nil {
+		flog.Printf("[overseer] loginserver login.json: %s\n", err)
+	}
+}
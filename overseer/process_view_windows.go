@@ -1,8 +1,10 @@
 package main
 
 import (
+	"fmt"
 	"sort"
 
+	"github.com/xackery/overseer/pkg/reporter"
 	"github.com/xackery/overseer/pkg/slog"
 	"github.com/xackery/wlk/walk"
 )
@@ -13,6 +15,10 @@ type ProcessViewEntry struct {
 	PID     string
 	Status  string
 	Uptime  string
+	CPU     float64
+	Memory  uint64
+	Threads int32
+	FDs     int32
 	checked bool
 }
 
@@ -62,6 +68,14 @@ func (m *ProcessView) Value(row, col int) interface{} {
 		return item.Status
 	case 3:
 		return item.Uptime
+	case 4:
+		return fmt.Sprintf("%.1f%%", item.CPU)
+	case 5:
+		return reporter.FormatBytes(item.Memory)
+	case 6:
+		return item.Threads
+	case 7:
+		return item.FDs
 	}
 
 	slog.Printf("invalid col: %d\n", col)
@@ -106,6 +120,14 @@ func (m *ProcessView) Sort(col int, order walk.SortOrder) error {
 			return c(a.Status < b.Status)
 		case 3:
 			return c(a.Uptime < b.Uptime)
+		case 4:
+			return c(a.CPU < b.CPU)
+		case 5:
+			return c(a.Memory < b.Memory)
+		case 6:
+			return c(a.Threads < b.Threads)
+		case 7:
+			return c(a.FDs < b.FDs)
 		}
 
 		slog.Printf("invalid sort col: %d", m.sortColumn)
@@ -1,31 +1,35 @@
 package api
 
 import (
-	"bufio"
-	"net"
+	"context"
 	"testing"
+
+	"github.com/xackery/overseer/pkg/telnet"
+	"github.com/xackery/overseer/pkg/telnet/telnettest"
 )
 
 func TestEndpoint(t *testing.T) {
-
-	conn, err := net.Dial("tcp", "localhost:9000")
+	server, err := telnettest.NewServer()
 	if err != nil {
-		t.Fatalf("Error connecting to server: %s", err)
+		t.Fatalf("Error starting server: %s", err)
 	}
-	defer conn.Close()
-
-	_, err = conn.Write([]byte("get_client_list"))
+	defer server.Close()
+	err = server.SetAPI("get_client_list", []any{})
 	if err != nil {
-		t.Fatalf("Error sending request: %s", err)
+		t.Fatalf("Error setting response: %s", err)
 	}
 
-	resp, err := bufio.NewReader(conn).ReadString('\n')
+	client := telnet.NewClient(server.Addr(), "", "")
+	defer client.Close()
+
+	resp := []map[string]any{}
+	err = client.API(context.Background(), "get_client_list", &resp)
 	if err != nil {
-		t.Fatalf("Error reading response: %s", err)
+		t.Fatalf("Error sending request: %s", err)
 	}
 
-	if resp != "[]" {
-		t.Fatalf("Expected empty list, got: %s", resp)
+	if len(resp) != 0 {
+		t.Fatalf("Expected empty list, got: %v", resp)
 	}
 
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 )
 
 // EQEmuConfiguration is the configuration for the EQEmu server
@@ -144,6 +145,19 @@ type LauncherConfig struct {
 	RunSharedMemory  bool   `json:"runSharedMemory"`
 }
 
+// StaticZoneNames returns the zone short names in StaticZones, which is comma separated
+func (l LauncherConfig) StaticZoneNames() []string {
+	names := []string{}
+	for _, name := range strings.Split(l.StaticZones, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
 // QuestsConfig is the configuration for the EQEmu server quests
 type QuestsConfig struct {
 	HotReload bool `json:"hotReload"`
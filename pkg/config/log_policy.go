@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/xackery/overseer/pkg/flog"
+)
+
+// LogPolicy describes where overseer.log and each app's log file are written, and how they rotate
+type LogPolicy struct {
+	// Dir is where app logs are written, such as logs/world.log, empty only logs to overseer.log
+	Dir      string
+	Rotation flog.Rotation
+}
+
+// DefaultLogPolicy is used when no log_* keys are set
+func DefaultLogPolicy() LogPolicy {
+	return LogPolicy{
+		Dir: "logs",
+		Rotation: flog.Rotation{
+			MaxSize:        10 * 1024 * 1024,
+			MaxFiles:       10,
+			IsCompressed:   true,
+			IsKeepPrevious: true,
+		},
+	}
+}
+
+// parse applies a log_* key to the policy, returns false if key is not a log key
+func (p *LogPolicy) parse(key string, value string) (bool, error) {
+	var err error
+	switch key {
+	case "log_dir":
+		p.Dir = value
+	case "log_max_size":
+		mb, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return true, fmt.Errorf("parse log_max_size: %w", err)
+		}
+		p.Rotation.MaxSize = mb * 1024 * 1024
+	case "log_rotate_interval":
+		p.Rotation.Interval, err = time.ParseDuration(value)
+		if err != nil {
+			return true, fmt.Errorf("parse log_rotate_interval: %w", err)
+		}
+	case "log_max_files":
+		p.Rotation.MaxFiles, err = strconv.Atoi(value)
+		if err != nil {
+			return true, fmt.Errorf("parse log_max_files: %w", err)
+		}
+	case "log_max_age":
+		p.Rotation.MaxAge, err = time.ParseDuration(value)
+		if err != nil {
+			return true, fmt.Errorf("parse log_max_age: %w", err)
+		}
+	case "log_compress":
+		p.Rotation.IsCompressed = isTrue(value)
+	case "log_keep_previous":
+		p.Rotation.IsKeepPrevious = isTrue(value)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
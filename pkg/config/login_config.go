@@ -0,0 +1,66 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// LoginConfiguration is the configuration for the EQEmu loginserver, login.json
+type LoginConfiguration struct {
+	Database            LoginDatabaseConfig `json:"database"`
+	ClientConfiguration LoginClientConfig   `json:"client_configuration"`
+	WorldServers        LoginWorldConfig    `json:"worldservers"`
+}
+
+// LoginDatabaseConfig is the database the loginserver keeps accounts in
+type LoginDatabaseConfig struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	DB       string `json:"db"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// LoginClientConfig is the ports clients connect to the loginserver on
+type LoginClientConfig struct {
+	TitaniumPort int `json:"titanium_port"`
+	SodPort      int `json:"sod_port"`
+}
+
+// LoginWorldConfig is how world servers are allowed to register with the loginserver
+type LoginWorldConfig struct {
+	UnregisteredAllowed bool `json:"unregistered_allowed"`
+	RejectDuplicates    bool `json:"reject_duplicate_servers"`
+}
+
+// LoadLoginConfig loads a loginserver configuration file
+func LoadLoginConfig(path string) (*LoginConfiguration, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var config LoginConfiguration
+	err = json.NewDecoder(r).Decode(&config)
+	if err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+
+	return &config, nil
+}
+
+// Verify returns an error if the loginserver is missing a setting it needs to start
+func (c *LoginConfiguration) Verify() error {
+	if c.Database.DB == "" {
+		return fmt.Errorf("database.db is empty")
+	}
+	if c.Database.Host == "" {
+		return fmt.Errorf("database.host is empty")
+	}
+	if c.ClientConfiguration.TitaniumPort == 0 && c.ClientConfiguration.SodPort == 0 {
+		return fmt.Errorf("client_configuration has no titanium_port or sod_port")
+	}
+	return nil
+}
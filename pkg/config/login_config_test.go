@@ -0,0 +1,19 @@
+package config
+
+import "testing"
+
+func TestLoginConfigVerify(t *testing.T) {
+	login := LoginConfiguration{
+		Database:            LoginDatabaseConfig{Host: "127.0.0.1", DB: "peq"},
+		ClientConfiguration: LoginClientConfig{TitaniumPort: 5998},
+	}
+	err := login.Verify()
+	if err != nil {
+		t.Fatalf("verify: %s", err)
+	}
+	login.ClientConfiguration.TitaniumPort = 0
+	err = login.Verify()
+	if err == nil {
+		t.Fatalf("expected an error without client ports")
+	}
+}
@@ -4,24 +4,142 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/xackery/overseer/pkg/rule"
 )
 
 type OverseerConfiguration struct {
 	BinPath    string
 	ServerPath string
 	ZoneCount  int
+	// MaxZones enables zone autoscaling when set, spawning up to this many zone processes instead of zone_count
+	MaxZones int
+	// MinZones is the fewest zone processes kept when autoscaling, 0 uses minZoneProcesses from eqemu_config.json
+	MinZones int
+	// IdleZones is how many sleeping zones autoscaling keeps available
+	IdleZones int
 	// Setup represents the setup type, options include default (bare-metal), docker (docker run), docker-compose (akk-stack)
 	Setup string
 	// If setup is docker, this is the network to use, defaults to eqemu
-	DockerNetwork        string
+	DockerNetwork string
+	// If setup is docker, this is the image apps run in, the server and bin paths are mounted into it
+	DockerImage          string
 	Expansion            string
 	PortableDatabase     int
 	AutoUpdate           int
 	Apps                 []string
 	IsScreenStart        bool
 	IsOverseerVerboseLog bool
+	// TelnetUsername and TelnetPassword log in to world's telnet console, not needed on localhost
+	TelnetUsername string
+	TelnetPassword string
+	// ControlAddress is where the control api listens, a host:port or unix:path, empty disables it
+	// and is the default
+	ControlAddress string
+	// ControlToken, when set, must be sent by every control api request
+	ControlToken string
+	// RestartPolicy is the default policy for every app
+	RestartPolicy RestartPolicy
+	// Watchdog is the default hang detection for every app
+	Watchdog WatchdogPolicy
+	// Recycle is the default recycling of idle zones
+	Recycle RecyclePolicy
+	// Log is where overseer and app logs are written and how they rotate
+	Log LogPolicy
+	// RestartSchedule restarts every app on a schedule, warning players first
+	RestartSchedule RestartSchedule
+	// Rules are log line rules for every app, a rule's app field limits it to one executable
+	Rules []*rule.Rule
+	// IsSharedMemoryPreflight runs shared_memory to completion before world starts
+	IsSharedMemoryPreflight bool
+	// ReadyTimeout is how long an app waits for its dependencies before starting anyway, 0 waits forever
+	ReadyTimeout time.Duration
+	// StopGrace is how long an app has to exit after being interrupted before it is killed
+	StopGrace time.Duration
+	// ShutdownTimeout is how long overseer waits for every app to stop when exiting
+	ShutdownTimeout time.Duration
+	// LeftoverAction is what to do with apps a previous overseer left running: ask, adopt, reap or ignore
+	LeftoverAction string
+	// AppConfigs are per-app [name] sections, keyed by name
+	AppConfigs map[string]*AppConfiguration
+	// StaticZones are the short names of static zones from eqemu_config.json, which fall back
+	// to the [zone] section like zone0 does
+	StaticZones []string
+}
+
+// AppConfiguration is a [name] section in overseer.ini, overriding settings for one app.
+// A section with exe set is a custom app, supervised like an app = line
+type AppConfiguration struct {
+	Name string
+	// Exe is the executable of a custom app, in bin_path unless it is an absolute path
+	Exe string
+	// DisplayName is what an app is shown and controlled as, defaults to the section name
+	DisplayName string
+	// Args are passed to the executable, split on spaces
+	Args []string
+	// Env are KEY=VALUE pairs added to the environment, one env line each
+	Env []string
+	// WorkingDir is where the app runs, relative to server_path, which is the default
+	WorkingDir string
+	// IsEnabled is false when the app should not be started at all
+	IsEnabled bool
+	// ReadyMatch marks the app running once a line of its output contains it
+	ReadyMatch string
+	// Rules are log line rules for the app, checked before the global and built in rules
+	Rules         []*rule.Rule
+	RestartPolicy RestartPolicy
+	Watchdog      WatchdogPolicy
+	Recycle       RecyclePolicy
+	// DependsOn lists apps that must be running before this app starts, nil uses the built in default
+	DependsOn    []string
+	ReadyTimeout time.Duration
+	StopGrace    time.Duration
+}
+
+const (
+	// DefaultDockerImage is used when docker_image is not set
+	DefaultDockerImage = "debian:stable-slim"
+	// DefaultReadyTimeout is used when ready_timeout is not set
+	DefaultReadyTimeout = 2 * time.Minute
+	// DefaultStopGrace is used when stop_grace is not set
+	DefaultStopGrace = 20 * time.Second
+	// DefaultShutdownTimeout is used when shutdown_timeout is not set
+	DefaultShutdownTimeout = time.Minute
+	// DefaultIdleZones is used when idle_zones is not set
+	DefaultIdleZones = 1
+)
+
+const (
+	// LeftoverAsk prompts for what to do with apps a previous overseer left running
+	LeftoverAsk = "ask"
+	// LeftoverAdopt watches leftover apps instead of starting new copies
+	LeftoverAdopt = "adopt"
+	// LeftoverReap stops leftover apps before starting new copies
+	LeftoverReap = "reap"
+	// LeftoverIgnore leaves leftover apps alone and starts new copies
+	LeftoverIgnore = "ignore"
+)
+
+// defaultOverseerConfig returns the config used for anything overseer.ini does not set
+func defaultOverseerConfig() *OverseerConfiguration {
+	return &OverseerConfiguration{
+		RestartPolicy:   DefaultRestartPolicy(),
+		ReadyTimeout:    DefaultReadyTimeout,
+		StopGrace:       DefaultStopGrace,
+		ShutdownTimeout: DefaultShutdownTimeout,
+		IdleZones:       DefaultIdleZones,
+		LeftoverAction:  LeftoverAsk,
+		AppConfigs:      make(map[string]*AppConfiguration),
+		DockerImage:     DefaultDockerImage,
+		Log:             DefaultLogPolicy(),
+		RestartSchedule: DefaultRestartSchedule(),
+
+		IsSharedMemoryPreflight: true,
+	}
 }
 
 // LoadOverseerConfig loads an overseer config file
@@ -37,21 +155,79 @@ func LoadOverseerConfig(path string) (*OverseerConfiguration, error) {
 	}
 	defer r.Close()
 
-	var config OverseerConfiguration
+	config := defaultOverseerConfig()
 
+	var app *AppConfiguration
 	reader := bufio.NewScanner(r)
 	for reader.Scan() {
 		line := reader.Text()
 		if strings.HasPrefix(line, "#") {
 			continue
 		}
-		if strings.Contains(line, "=") {
-			parts := strings.Split(line, "=")
-			if len(parts) != 2 {
-				continue
+		name, ok := sectionName(line)
+		if ok {
+			app, ok = config.AppConfigs[name]
+			if !ok {
+				app = &AppConfiguration{
+					Name:          name,
+					IsEnabled:     true,
+					RestartPolicy: config.RestartPolicy,
+					Watchdog:      config.Watchdog,
+					Recycle:       config.Recycle,
+					ReadyTimeout:  config.ReadyTimeout,
+					StopGrace:     config.StopGrace,
+				}
+				config.AppConfigs[name] = app
 			}
+			continue
+		}
+		if strings.Contains(line, "=") {
+			// only the first = splits, so values such as env = KEY=VALUE keep theirs
+			parts := strings.SplitN(line, "=", 2)
 			key := strings.ToLower(strings.TrimSpace(parts[0]))
 			value := strings.TrimSpace(parts[1])
+			if app != nil {
+				err = app.parse(key, value)
+				if err != nil {
+					return nil, fmt.Errorf("[%s]: %w", app.Name, err)
+				}
+				continue
+			}
+			isRestartKey, err := config.RestartPolicy.parse(key, value)
+			if err != nil {
+				return nil, err
+			}
+			if isRestartKey {
+				continue
+			}
+			isWatchdogKey, err := config.Watchdog.parse(key, value)
+			if err != nil {
+				return nil, err
+			}
+			if isWatchdogKey {
+				continue
+			}
+			isRecycleKey, err := config.Recycle.parse(key, value)
+			if err != nil {
+				return nil, err
+			}
+			if isRecycleKey {
+				continue
+			}
+			isLogKey, err := config.Log.parse(key, value)
+			if err != nil {
+				return nil, err
+			}
+			if isLogKey {
+				continue
+			}
+			isScheduleKey, err := config.RestartSchedule.parse(key, value)
+			if err != nil {
+				return nil, err
+			}
+			if isScheduleKey {
+				continue
+			}
 			switch key {
 			case "bin_path":
 				config.BinPath = value
@@ -68,6 +244,21 @@ func LoadOverseerConfig(path string) (*OverseerConfiguration, error) {
 				if err != nil {
 					return nil, fmt.Errorf("parse zone_count: %w", err)
 				}
+			case "min_zones":
+				config.MinZones, err = strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("parse min_zones: %w", err)
+				}
+			case "max_zones":
+				config.MaxZones, err = strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("parse max_zones: %w", err)
+				}
+			case "idle_zones":
+				config.IdleZones, err = strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("parse idle_zones: %w", err)
+				}
 			case "auto_update":
 				config.AutoUpdate, err = strconv.Atoi(value)
 				if err != nil {
@@ -90,6 +281,8 @@ func LoadOverseerConfig(path string) (*OverseerConfiguration, error) {
 				}
 
 				config.DockerNetwork = value
+			case "docker_image":
+				config.DockerImage = value
 			case "expansion":
 				value := strings.ToLower(value)
 				if !IsValidExpansion(value) {
@@ -104,34 +297,284 @@ func LoadOverseerConfig(path string) (*OverseerConfiguration, error) {
 			case "app":
 				config.Apps = append(config.Apps, value)
 			case "is_screen_start":
-				val, err := strconv.Atoi(value)
+				config.IsScreenStart = isTrue(value)
+			case "is_overseer_verbose_log":
+				config.IsOverseerVerboseLog = isTrue(value)
+			case "is_shared_memory_preflight":
+				config.IsSharedMemoryPreflight = isTrue(value)
+			case "control_address":
+				config.ControlAddress = value
+			case "control_token":
+				config.ControlToken = value
+			case "telnet_username":
+				config.TelnetUsername = value
+			case "telnet_password":
+				config.TelnetPassword = value
+			case "ready_timeout":
+				config.ReadyTimeout, err = time.ParseDuration(value)
 				if err != nil {
-					config.IsScreenStart = false
-					if strings.EqualFold(value, "true") {
-						config.IsScreenStart = true
-					}
+					return nil, fmt.Errorf("parse ready_timeout: %w", err)
 				}
-				if val == 1 {
-					config.IsScreenStart = true
+			case "stop_grace":
+				config.StopGrace, err = time.ParseDuration(value)
+				if err != nil {
+					return nil, fmt.Errorf("parse stop_grace: %w", err)
 				}
-			case "is_overseer_verbose_log":
-				val, err := strconv.Atoi(value)
+			case "shutdown_timeout":
+				config.ShutdownTimeout, err = time.ParseDuration(value)
+				if err != nil {
+					return nil, fmt.Errorf("parse shutdown_timeout: %w", err)
+				}
+			case "rule":
+				r, err := rule.Parse(value)
 				if err != nil {
-					config.IsOverseerVerboseLog = false
-					if strings.EqualFold(value, "true") {
-						config.IsOverseerVerboseLog = true
-					}
+					return nil, err
 				}
-				if val == 1 {
-					config.IsOverseerVerboseLog = true
+				config.Rules = append(config.Rules, r)
+			case "leftover_action":
+				value = strings.ToLower(value)
+				switch value {
+				case LeftoverAsk, LeftoverAdopt, LeftoverReap, LeftoverIgnore:
+				default:
+					return nil, fmt.Errorf("leftover_action must be %s, %s, %s or %s, got %s", LeftoverAsk, LeftoverAdopt, LeftoverReap, LeftoverIgnore, value)
 				}
+				config.LeftoverAction = value
 			default:
 				return nil, fmt.Errorf("unknown key in overseer.ini: %s", key)
 			}
 		}
 	}
 
-	return &config, nil
+	return config, nil
+}
+
+// parse applies a key inside a [name] section
+func (a *AppConfiguration) parse(key string, value string) error {
+	isRestartKey, err := a.RestartPolicy.parse(key, value)
+	if err != nil {
+		return err
+	}
+	if isRestartKey {
+		return nil
+	}
+	isWatchdogKey, err := a.Watchdog.parse(key, value)
+	if err != nil {
+		return err
+	}
+	if isWatchdogKey {
+		return nil
+	}
+	isRecycleKey, err := a.Recycle.parse(key, value)
+	if err != nil {
+		return err
+	}
+	if isRecycleKey {
+		return nil
+	}
+	switch key {
+	case "exe":
+		a.Exe = value
+	case "display_name":
+		// overseer names the zones it spawns, and finds them again by that name
+		if a.Name == "zone" || isDynamicZone(a.Name) {
+			return fmt.Errorf("display_name can't be set for dynamic zones")
+		}
+		a.DisplayName = value
+	case "args":
+		a.Args = strings.Fields(value)
+	case "env":
+		if !strings.Contains(value, "=") {
+			return fmt.Errorf("env must be KEY=VALUE, got %s", value)
+		}
+		a.Env = append(a.Env, value)
+	case "working_dir":
+		a.WorkingDir = value
+	case "enabled":
+		a.IsEnabled = isTrue(value)
+	case "ready_match":
+		a.ReadyMatch = value
+	case "rule":
+		r, err := rule.Parse(value)
+		if err != nil {
+			return err
+		}
+		a.Rules = append(a.Rules, r)
+	case "depends_on":
+		a.DependsOn = []string{}
+		for _, dep := range strings.Split(value, ",") {
+			dep = strings.ToLower(strings.TrimSpace(dep))
+			if dep == "" {
+				continue
+			}
+			a.DependsOn = append(a.DependsOn, dep)
+		}
+	case "ready_timeout":
+		a.ReadyTimeout, err = time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("parse ready_timeout: %w", err)
+		}
+	case "stop_grace":
+		a.StopGrace, err = time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("parse stop_grace: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown key in overseer.ini: %s", key)
+	}
+	return nil
+}
+
+// isTrue reads a 1 or true flag
+func isTrue(value string) bool {
+	val, err := strconv.Atoi(value)
+	if err != nil {
+		return strings.EqualFold(value, "true")
+	}
+	return val == 1
+}
+
+// sectionName returns the name of a [name] section line
+func sectionName(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+		return "", false
+	}
+	return strings.ToLower(strings.TrimSpace(line[1 : len(line)-1])), true
+}
+
+// AppConfig returns the [name] section for an app. Zones fall back to the [zone] section, so it
+// applies to zone0, zone1 and static zones such as qeynos2. Returns nil if neither exist
+func (c *OverseerConfiguration) AppConfig(name string) *AppConfiguration {
+	name = strings.ToLower(name)
+	app, ok := c.AppConfigs[name]
+	if ok {
+		return app
+	}
+	if !c.isZone(name) {
+		return nil
+	}
+	return c.AppConfigs["zone"]
+}
+
+// isZone returns true if name is a dynamic or static zone
+func (c *OverseerConfiguration) isZone(name string) bool {
+	if isDynamicZone(name) {
+		return true
+	}
+	for _, zone := range c.StaticZones {
+		if strings.EqualFold(zone, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDynamicZone returns true if name is zone followed by a number, such as zone3
+func isDynamicZone(name string) bool {
+	number, ok := strings.CutPrefix(name, "zone")
+	if !ok || number == "" {
+		return false
+	}
+	_, err := strconv.Atoi(number)
+	return err == nil
+}
+
+// IsEnabled returns false if the section of an app turns it off
+func (c *OverseerConfiguration) IsEnabled(name string) bool {
+	app := c.AppConfig(name)
+	if app == nil {
+		return true
+	}
+	return app.IsEnabled
+}
+
+// CustomApps returns each section that sets exe, sorted by name
+func (c *OverseerConfiguration) CustomApps() []*AppConfiguration {
+	apps := []*AppConfiguration{}
+	for _, app := range c.AppConfigs {
+		if app.Exe == "" {
+			continue
+		}
+		apps = append(apps, app)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].Name < apps[j].Name
+	})
+	return apps
+}
+
+// RestartPolicyFor returns the restart policy of an app
+func (c *OverseerConfiguration) RestartPolicyFor(name string) RestartPolicy {
+	app := c.AppConfig(name)
+	if app == nil {
+		return c.RestartPolicy
+	}
+	return app.RestartPolicy
+}
+
+// WatchdogFor returns the hang detection policy of an app
+func (c *OverseerConfiguration) WatchdogFor(name string) WatchdogPolicy {
+	app := c.AppConfig(name)
+	if app == nil {
+		return c.Watchdog
+	}
+	return app.Watchdog
+}
+
+// RecycleFor returns the idle recycling policy of an app
+func (c *OverseerConfiguration) RecycleFor(name string) RecyclePolicy {
+	app := c.AppConfig(name)
+	if app == nil {
+		return c.Recycle
+	}
+	return app.Recycle
+}
+
+// RulesFor returns the log line rules of an app, its own before the global ones
+func (c *OverseerConfiguration) RulesFor(name string) []*rule.Rule {
+	rules := []*rule.Rule{}
+	app := c.AppConfig(name)
+	if app != nil {
+		rules = append(rules, app.Rules...)
+	}
+	return append(rules, c.Rules...)
+}
+
+// DisplayNameFor returns what an app is shown and controlled as. Only its own section can
+// rename it, a shared [zone] section names no single app
+func (c *OverseerConfiguration) DisplayNameFor(name string) string {
+	app := c.AppConfig(name)
+	if app == nil || app.Name != name || app.DisplayName == "" {
+		return name
+	}
+	return app.DisplayName
+}
+
+// DependsOnFor returns the apps an app waits on before starting, or defaults if its section does not set depends_on
+func (c *OverseerConfiguration) DependsOnFor(name string, defaults ...string) []string {
+	app := c.AppConfig(name)
+	if app == nil || app.DependsOn == nil {
+		return defaults
+	}
+	return app.DependsOn
+}
+
+// ReadyTimeoutFor returns how long an app waits on its dependencies
+func (c *OverseerConfiguration) ReadyTimeoutFor(name string) time.Duration {
+	app := c.AppConfig(name)
+	if app == nil {
+		return c.ReadyTimeout
+	}
+	return app.ReadyTimeout
+}
+
+// StopGraceFor returns how long an app has to exit after being interrupted
+func (c *OverseerConfiguration) StopGraceFor(name string) time.Duration {
+	app := c.AppConfig(name)
+	if app == nil {
+		return c.StopGrace
+	}
+	return app.StopGrace
 }
 
 func IsValidExpansion(name string) bool {
@@ -214,9 +657,16 @@ func (c *OverseerConfiguration) Save() error {
 	tmpConfig := OverseerConfiguration{}
 
 	out := ""
+	// sections are kept as is, and after every global key
+	sectionOut := ""
 	reader := bufio.NewScanner(r)
 	for reader.Scan() {
 		line := reader.Text()
+		_, isSection := sectionName(line)
+		if isSection || sectionOut != "" {
+			sectionOut += line + "\n"
+			continue
+		}
 		if strings.HasPrefix(line, "#") {
 			out += line + "\n"
 			continue
@@ -225,13 +675,9 @@ func (c *OverseerConfiguration) Save() error {
 			out += line + "\n"
 			continue
 		}
-		parts := strings.Split(line, "=")
-		if len(parts) != 2 {
-			continue
-		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
+		key, value, _ := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
 		switch key {
 		case "bin_path":
 			if tmpConfig.BinPath == "1" {
@@ -359,6 +805,7 @@ func (c *OverseerConfiguration) Save() error {
 		val = 1
 	}
 	out += fmt.Sprintf("is_overseer_verbose_log = %d\n", val)
+	out += sectionOut
 
 	err = os.WriteFile("overseer.ini", []byte(out), 0644)
 	if err != nil {
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadOverseerConfigAppSection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overseer.ini")
+	err := os.WriteFile(path, []byte(`zone_count = 2
+[bot]
+exe = bots/bot
+display_name = chatbot
+args = --server localhost --verbose
+env = BOT_TOKEN=abc=123
+env = BOT_MODE=live
+ready_match = logged in
+[ucs]
+enabled = 0
+`), 0644)
+	if err != nil {
+		t.Fatalf("write: %s", err)
+	}
+
+	cfg, err := LoadOverseerConfig(path)
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	apps := cfg.CustomApps()
+	if len(apps) != 1 {
+		t.Fatalf("expected 1 custom app, got %d", len(apps))
+	}
+	bot := apps[0]
+	if bot.Exe != "bots/bot" || bot.DisplayName != "chatbot" || bot.ReadyMatch != "logged in" {
+		t.Fatalf("unexpected bot section: %+v", bot)
+	}
+	if len(bot.Args) != 3 || bot.Args[2] != "--verbose" {
+		t.Fatalf("unexpected args: %v", bot.Args)
+	}
+	if len(bot.Env) != 2 || bot.Env[0] != "BOT_TOKEN=abc=123" {
+		t.Fatalf("unexpected env: %v", bot.Env)
+	}
+	if cfg.IsEnabled("ucs") || !cfg.IsEnabled("world") || !cfg.IsEnabled("bot") {
+		t.Fatalf("expected only ucs to be disabled")
+	}
+}
+
+func TestAppConfigZones(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overseer.ini")
+	err := os.WriteFile(path, []byte(`[zone]
+stop_grace = 5s
+[qeynos]
+enabled = 0
+`), 0644)
+	if err != nil {
+		t.Fatalf("write: %s", err)
+	}
+
+	cfg, err := LoadOverseerConfig(path)
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	cfg.StaticZones = []string{"qeynos2"}
+	for _, name := range []string{"zone0", "zone12", "qeynos2"} {
+		if cfg.StopGraceFor(name) != 5*time.Second || !cfg.IsEnabled(name) {
+			t.Fatalf("expected %s to use the [zone] section", name)
+		}
+	}
+	if cfg.AppConfig("zones") != nil || cfg.AppConfig("world2") != nil {
+		t.Fatalf("expected only zones to fall back to [zone]")
+	}
+}
+
+func TestDisplayNameZone(t *testing.T) {
+	for _, section := range []string{"zone", "zone3"} {
+		path := filepath.Join(t.TempDir(), "overseer.ini")
+		err := os.WriteFile(path, []byte("["+section+"]\ndisplay_name = zones\n"), 0644)
+		if err != nil {
+			t.Fatalf("write: %s", err)
+		}
+		_, err = LoadOverseerConfig(path)
+		if err == nil {
+			t.Fatalf("expected [%s] display_name to be rejected", section)
+		}
+	}
+}
+
+func TestLoadOverseerConfigRestartSchedule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overseer.ini")
+	err := os.WriteFile(path, []byte(`restart_schedule = 0 4 * * *
+restart_warnings = 1m, 10s, 15m
+restart_shared_memory = 0
+restart_update = update --force
+`), 0644)
+	if err != nil {
+		t.Fatalf("write: %s", err)
+	}
+
+	cfg, err := LoadOverseerConfig(path)
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	schedule := cfg.RestartSchedule
+	if schedule.Cron == nil || schedule.Cron.String() != "0 4 * * *" {
+		t.Fatalf("unexpected cron: %v", schedule.Cron)
+	}
+	if len(schedule.Warnings) != 3 || schedule.Warnings[0] != 15*time.Minute || schedule.Warnings[2] != 10*time.Second {
+		t.Fatalf("expected warnings longest first, got %v", schedule.Warnings)
+	}
+	if schedule.IsSharedMemory || len(schedule.Update) != 2 || schedule.Update[1] != "--force" {
+		t.Fatalf("unexpected schedule: %+v", schedule)
+	}
+
+	err = os.WriteFile(path, []byte("restart_schedule = 0 25 * * *\n"), 0644)
+	if err != nil {
+		t.Fatalf("write: %s", err)
+	}
+	_, err = LoadOverseerConfig(path)
+	if err == nil {
+		t.Fatalf("expected invalid hour error")
+	}
+}
+
+func TestSaveKeepsEquals(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %s", err)
+	}
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatalf("chdir: %s", err)
+	}
+	defer os.Chdir(wd)
+
+	err = os.WriteFile("overseer.ini", []byte("control_token = abc=123\n"), 0644)
+	if err != nil {
+		t.Fatalf("write: %s", err)
+	}
+	cfg, err := LoadOverseerConfig("overseer.ini")
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	err = cfg.Save()
+	if err != nil {
+		t.Fatalf("save: %s", err)
+	}
+	cfg, err = LoadOverseerConfig("overseer.ini")
+	if err != nil {
+		t.Fatalf("load saved: %s", err)
+	}
+	if cfg.ControlToken != "abc=123" {
+		t.Fatalf("expected control_token abc=123, got %q", cfg.ControlToken)
+	}
+}
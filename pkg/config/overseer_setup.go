@@ -16,7 +16,7 @@ func overseerSetup() (*OverseerConfiguration, error) {
 	message.Banner("Initial Setup")
 	fmt.Println("Since no overseer.ini file was found, let's do some quick setup")
 
-	config := &OverseerConfiguration{}
+	config := defaultOverseerConfig()
 	err := ConfigSetup(config)
 	if err != nil {
 		return nil, fmt.Errorf("config setup: %w", err)
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// RecyclePolicy describes when an idle zone is restarted to reclaim leaked memory. Zones are only
+// recycled while sleeping, so no players are kicked. A zero value disables that check
+type RecyclePolicy struct {
+	// MemoryLimit recycles a sleeping zone once its resident memory exceeds this many bytes
+	MemoryLimit uint64
+	// Interval recycles a sleeping zone once it has been up this long
+	Interval time.Duration
+}
+
+// parse applies a recycle_* key to the policy, returns false if key is not a recycle key
+func (p *RecyclePolicy) parse(key string, value string) (bool, error) {
+	switch key {
+	case "recycle_memory":
+		mb, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return true, fmt.Errorf("parse recycle_memory: %w", err)
+		}
+		p.MemoryLimit = mb * 1024 * 1024
+	case "recycle_interval":
+		var err error
+		p.Interval, err = time.ParseDuration(value)
+		if err != nil {
+			return true, fmt.Errorf("parse recycle_interval: %w", err)
+		}
+	default:
+		return false, nil
+	}
+	return true, nil
+}
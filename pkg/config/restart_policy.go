@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// RestartAlways respawns an app whenever it exits
+	RestartAlways = "always"
+	// RestartOnFailure respawns an app only when it exits with an error
+	RestartOnFailure = "on-failure"
+	// RestartNever leaves an app stopped once it exits
+	RestartNever = "never"
+)
+
+// RestartPolicy describes how an app is respawned when it exits
+type RestartPolicy struct {
+	// Mode is always, on-failure or never
+	Mode string
+	// MaxRestarts within Window puts an app in a crash loop, 0 is unlimited
+	MaxRestarts int
+	Window      time.Duration
+	// Backoff is the first restart delay, doubled per consecutive crash up to BackoffMax
+	Backoff    time.Duration
+	BackoffMax time.Duration
+	// Jitter randomizes each delay by up to this fraction, 0 to 1
+	Jitter float64
+	// ResetAfter is how long an app must run for its backoff to reset
+	ResetAfter time.Duration
+}
+
+// DefaultRestartPolicy returns the policy used when overseer.ini does not set one
+func DefaultRestartPolicy() RestartPolicy {
+	return RestartPolicy{
+		Mode:        RestartAlways,
+		MaxRestarts: 10,
+		Window:      10 * time.Minute,
+		Backoff:     10 * time.Second,
+		BackoffMax:  60 * time.Second,
+		Jitter:      0.1,
+		ResetAfter:  3 * time.Minute,
+	}
+}
+
+// parse applies a restart_* key to the policy, returns false if key is not a restart key
+func (p *RestartPolicy) parse(key string, value string) (bool, error) {
+	var err error
+	switch key {
+	case "restart_policy":
+		value = strings.ToLower(value)
+		switch value {
+		case RestartAlways, RestartOnFailure, RestartNever:
+		default:
+			return true, fmt.Errorf("restart_policy %s is not always, on-failure or never", value)
+		}
+		p.Mode = value
+	case "restart_max":
+		p.MaxRestarts, err = strconv.Atoi(value)
+		if err != nil {
+			return true, fmt.Errorf("parse restart_max: %w", err)
+		}
+	case "restart_window":
+		p.Window, err = time.ParseDuration(value)
+		if err != nil {
+			return true, fmt.Errorf("parse restart_window: %w", err)
+		}
+	case "restart_backoff":
+		p.Backoff, err = time.ParseDuration(value)
+		if err != nil {
+			return true, fmt.Errorf("parse restart_backoff: %w", err)
+		}
+	case "restart_backoff_max":
+		p.BackoffMax, err = time.ParseDuration(value)
+		if err != nil {
+			return true, fmt.Errorf("parse restart_backoff_max: %w", err)
+		}
+	case "restart_jitter":
+		p.Jitter, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			return true, fmt.Errorf("parse restart_jitter: %w", err)
+		}
+		if p.Jitter < 0 || p.Jitter > 1 {
+			return true, fmt.Errorf("restart_jitter must be between 0 and 1")
+		}
+	case "restart_reset":
+		p.ResetAfter, err = time.ParseDuration(value)
+		if err != nil {
+			return true, fmt.Errorf("parse restart_reset: %w", err)
+		}
+	default:
+		return false, nil
+	}
+	return true, nil
+}
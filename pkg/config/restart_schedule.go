@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xackery/overseer/pkg/cron"
+)
+
+// RestartSchedule describes a scheduled restart of every app, with countdown warnings
+// broadcast to players beforehand. A nil Cron disables it
+type RestartSchedule struct {
+	Cron *cron.Schedule
+	// Warnings are how long before the restart players are warned, longest first
+	Warnings []time.Duration
+	// IsSharedMemory runs the shared_memory preflight while everything is stopped
+	IsSharedMemory bool
+	// Update is an executable in bin_path and its args, run while everything is stopped
+	Update []string
+}
+
+// DefaultRestartSchedule is used when no restart_* keys are set
+func DefaultRestartSchedule() RestartSchedule {
+	return RestartSchedule{
+		Warnings:       []time.Duration{15 * time.Minute, 5 * time.Minute, time.Minute, 10 * time.Second},
+		IsSharedMemory: true,
+	}
+}
+
+// parse applies a restart_schedule, restart_warnings, restart_shared_memory or restart_update key,
+// returns false if key is not one of them
+func (s *RestartSchedule) parse(key string, value string) (bool, error) {
+	switch key {
+	case "restart_schedule":
+		if value == "" {
+			s.Cron = nil
+			return true, nil
+		}
+		schedule, err := cron.Parse(value)
+		if err != nil {
+			return true, fmt.Errorf("parse restart_schedule: %w", err)
+		}
+		s.Cron = schedule
+	case "restart_warnings":
+		s.Warnings = []time.Duration{}
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			warning, err := time.ParseDuration(field)
+			if err != nil {
+				return true, fmt.Errorf("parse restart_warnings: %w", err)
+			}
+			s.Warnings = append(s.Warnings, warning)
+		}
+		sort.Slice(s.Warnings, func(i, j int) bool {
+			return s.Warnings[i] > s.Warnings[j]
+		})
+	case "restart_shared_memory":
+		s.IsSharedMemory = isTrue(value)
+	case "restart_update":
+		s.Update = strings.Fields(value)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
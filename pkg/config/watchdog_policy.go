@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// WatchdogPolicy describes when a running app is considered hung. A zero timeout disables that check
+type WatchdogPolicy struct {
+	// OutputTimeout flags an app that has written nothing for this long, sleeping zones are skipped
+	OutputTimeout time.Duration
+	// CPUTimeout flags an app that has used a full core for this long
+	CPUTimeout time.Duration
+	// TelnetTimeout flags world when its telnet console has not answered for this long
+	TelnetTimeout time.Duration
+	// IsRestart restarts a hung app, following its restart policy
+	IsRestart bool
+}
+
+// parse applies a watchdog_* key to the policy, returns false if key is not a watchdog key
+func (p *WatchdogPolicy) parse(key string, value string) (bool, error) {
+	var err error
+	switch key {
+	case "watchdog_output":
+		p.OutputTimeout, err = time.ParseDuration(value)
+		if err != nil {
+			return true, fmt.Errorf("parse watchdog_output: %w", err)
+		}
+	case "watchdog_cpu":
+		p.CPUTimeout, err = time.ParseDuration(value)
+		if err != nil {
+			return true, fmt.Errorf("parse watchdog_cpu: %w", err)
+		}
+	case "watchdog_telnet":
+		p.TelnetTimeout, err = time.ParseDuration(value)
+		if err != nil {
+			return true, fmt.Errorf("parse watchdog_telnet: %w", err)
+		}
+	case "watchdog_restart":
+		p.IsRestart = isTrue(value)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
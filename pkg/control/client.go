@@ -0,0 +1,143 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xackery/overseer/pkg/eqlog"
+	"github.com/xackery/overseer/pkg/reporter"
+)
+
+// unixHost is the host name of requests over a unix socket
+const unixHost = "overseer"
+
+// Client talks to the control api of a running overseer
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewClient creates a client for the control api on address, a host:port or unix:path,
+// token is the control_token of overseer.ini
+func NewClient(address string, token string) *Client {
+	c := &Client{
+		baseURL: "http://" + address,
+		token:   token,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+	if strings.HasPrefix(address, "unix:") {
+		path := strings.TrimPrefix(address, "unix:")
+		c.baseURL = "http://" + unixHost
+		c.http.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}
+	}
+	return c
+}
+
+// Apps returns every app overseer is tracking
+func (c *Client) Apps(ctx context.Context) ([]reporter.AppReport, error) {
+	resp := appsResponse{}
+	err := c.do(ctx, http.MethodGet, "/apps", &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Apps, nil
+}
+
+// App returns a single app
+func (c *Client) App(ctx context.Context, name string) (*reporter.AppReport, error) {
+	report := &reporter.AppReport{}
+	err := c.do(ctx, http.MethodGet, "/apps/"+url.PathEscape(name), report)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Metrics returns the resource usage history of an app, oldest sample first
+func (c *Client) Metrics(ctx context.Context, name string) ([]reporter.Metrics, error) {
+	resp := metricsResponse{}
+	err := c.do(ctx, http.MethodGet, "/apps/"+url.PathEscape(name)+"/metrics", &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Metrics, nil
+}
+
+// Logs returns recent parsed log lines at or above severity, from app if it is not empty, oldest first
+func (c *Client) Logs(ctx context.Context, app string, severity eqlog.Severity, limit int) ([]eqlog.Event, error) {
+	query := url.Values{}
+	if app != "" {
+		query.Set("app", app)
+	}
+	query.Set("severity", severity.String())
+	query.Set("limit", strconv.Itoa(limit))
+	resp := logsResponse{}
+	err := c.do(ctx, http.MethodGet, "/logs?"+query.Encode(), &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Events, nil
+}
+
+// Players returns the online players sorted by name, and how many each zone process hosts, most first
+func (c *Client) Players(ctx context.Context) ([]reporter.Player, []reporter.ZonePopulation, error) {
+	resp := playersResponse{}
+	err := c.do(ctx, http.MethodGet, "/players", &resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Players, resp.Zones, nil
+}
+
+// Command sends an action (restart, stop, start, hold, resume) to an app
+func (c *Client) Command(ctx context.Context, name string, action string) error {
+	return c.do(ctx, http.MethodPost, "/apps/"+url.PathEscape(name)+"/"+url.PathEscape(action), nil)
+}
+
+// RestartAll asks overseer to stop everything, run preflight apps and start everything again
+func (c *Client) RestartAll(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/restart-all", nil)
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set(TokenHeader, c.token)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		errResp := errorResponse{}
+		err = json.NewDecoder(resp.Body).Decode(&errResp)
+		if err != nil || errResp.Error == "" {
+			return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, errResp.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	err = json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	return nil
+}
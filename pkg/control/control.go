@@ -0,0 +1,344 @@
+package control
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xackery/overseer/pkg/eqlog"
+	"github.com/xackery/overseer/pkg/flog"
+	"github.com/xackery/overseer/pkg/manager"
+	"github.com/xackery/overseer/pkg/reporter"
+	"github.com/xackery/overseer/pkg/signal"
+)
+
+const (
+	// defaultLogLimit is how many events GET /logs returns without ?limit=
+	defaultLogLimit = 100
+	// TokenHeader must be on every request, set to the control token if one is configured.
+	// Browsers can't send it cross origin without a preflight, which the api never allows
+	TokenHeader = "X-Overseer-Token"
+)
+
+var (
+	mu       sync.Mutex
+	server   *http.Server
+	listener net.Listener
+)
+
+// errorResponse is returned on any failed request
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// appsResponse is returned by GET /apps
+type appsResponse struct {
+	Apps []reporter.AppReport `json:"apps"`
+}
+
+// logsResponse is returned by GET /logs, oldest event first
+type logsResponse struct {
+	Events []eqlog.Event `json:"events"`
+}
+
+// playersResponse is returned by GET /players, players sorted by name and zones by most players
+type playersResponse struct {
+	Players []reporter.Player         `json:"players"`
+	Zones   []reporter.ZonePopulation `json:"zones"`
+}
+
+// metricsResponse is returned by GET /apps/{name}/metrics, oldest sample first
+type metricsResponse struct {
+	Metrics []reporter.Metrics `json:"metrics"`
+}
+
+// New starts the control api on address, a host:port or unix:path. If token is not empty,
+// requests must send it in TokenHeader. A host:port needs a token, since any local user can
+// connect to it, while a unix socket is only opened by the user running overseer
+func New(address string, token string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if server != nil {
+		return fmt.Errorf("already started")
+	}
+	if token == "" && !strings.HasPrefix(address, "unix:") {
+		return fmt.Errorf("%s needs control_token set, or use a unix:path address", address)
+	}
+
+	var err error
+	listener, err = listen(address)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", address, err)
+	}
+
+	server = &http.Server{
+		Handler:      Handler(token),
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	go func(srv *http.Server, l net.Listener) {
+		err := srv.Serve(l)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			flog.Printf("[control] serve: %s\n", err)
+		}
+	}(server, listener)
+
+	flog.Printf("[control] listening on %s\n", address)
+	return nil
+}
+
+// Close stops the control api
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if server == nil {
+		return nil
+	}
+	err := server.Close()
+	server = nil
+	listener = nil
+	if err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+	return nil
+}
+
+func listen(address string) (net.Listener, error) {
+	if !strings.HasPrefix(address, "unix:") {
+		return net.Listen("tcp", address)
+	}
+	path := strings.TrimPrefix(address, "unix:")
+	// a socket left over from a crashed run would block listening
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("remove stale socket: %w", err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// only the user running overseer may control it
+	err = os.Chmod(path, 0600)
+	if err != nil {
+		l.Close()
+		return nil, fmt.Errorf("chmod socket: %w", err)
+	}
+	return l, nil
+}
+
+// Handler returns the control api routes
+//
+//	GET  /apps                 list all apps
+//	GET  /apps/{name}          show one app
+//	GET  /apps/{name}/metrics  show the resource usage history of an app
+//	POST /apps/{name}/{action} restart, stop, start, hold or resume an app
+//	POST /restart-all          stop everything, run preflight apps, start everything
+//	GET  /logs                 recent parsed log lines, filtered by ?app=, ?severity= and ?limit=
+//	GET  /players              online players and how many each zone process hosts
+//
+// Every request must send TokenHeader, set to token if it is not empty
+func Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apps", onApps)
+	mux.HandleFunc("/apps/", onApp)
+	mux.HandleFunc("/restart-all", onRestartAll)
+	mux.HandleFunc("/logs", onLogs)
+	mux.HandleFunc("/players", onPlayers)
+	return guard(token, mux)
+}
+
+// guard rejects requests that could come from a web page open on the host, since the api
+// listens on localhost. A cross origin page can't set TokenHeader, and a page using dns
+// rebinding to look same origin sends its own host name
+func guard(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isLocalHost(r.Host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %s not allowed", r.Host))
+			return
+		}
+		values, ok := r.Header[http.CanonicalHeaderKey(TokenHeader)]
+		if !ok {
+			writeError(w, http.StatusForbidden, fmt.Errorf("missing %s header", TokenHeader))
+			return
+		}
+		if token != "" && (len(values) != 1 || subtle.ConstantTimeCompare([]byte(values[0]), []byte(token)) != 1) {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid %s", TokenHeader))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLocalHost reports if a request's host is an ip address, localhost, or the name used over
+// a unix socket. Any other name was resolved by someone else's dns
+func isLocalHost(host string) bool {
+	name, _, err := net.SplitHostPort(host)
+	if err != nil {
+		name = host
+	}
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "["), "]")
+	if net.ParseIP(name) != nil {
+		return true
+	}
+	return strings.EqualFold(name, "localhost") || name == unixHost
+}
+
+func onRestartAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	flog.Printf("[control] restart all\n")
+	// restarting everything outlives the request, failures surface as an alert
+	go func() {
+		err := manager.RestartAll(signal.Ctx(), manager.ShutdownTimeout)
+		if err != nil {
+			flog.Printf("[control] restart all: %s\n", err)
+		}
+	}()
+	writeJSON(w, http.StatusAccepted, appsResponse{Apps: reporter.Reports()})
+}
+
+func onLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	query := r.URL.Query()
+	severity := eqlog.SeverityInfo
+	if query.Get("severity") != "" {
+		var ok bool
+		severity, ok = eqlog.ParseSeverity(query.Get("severity"))
+		if !ok {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unknown severity %s", query.Get("severity")))
+			return
+		}
+	}
+	limit := defaultLogLimit
+	if query.Get("limit") != "" {
+		var err error
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("limit must be a positive number"))
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, logsResponse{Events: eqlog.Recent(query.Get("app"), severity, limit)})
+}
+
+func onPlayers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	writeJSON(w, http.StatusOK, playersResponse{Players: reporter.World().Players, Zones: reporter.ZonePopulations()})
+}
+
+func onApps(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	writeJSON(w, http.StatusOK, appsResponse{Apps: reporter.Reports()})
+}
+
+func onApp(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/apps/"), "/"), "/")
+	name := parts[0]
+	if name == "" {
+		onApps(w, r)
+		return
+	}
+
+	switch len(parts) {
+	case 1:
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		report, ok := reporter.Report(name)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("%s: %w", name, manager.ErrAppNotFound))
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	case 2:
+		if parts[1] == "metrics" {
+			onMetrics(w, r, name)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		onAction(w, name, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown path %s", r.URL.Path))
+	}
+}
+
+func onMetrics(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	_, ok := reporter.Report(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s: %w", name, manager.ErrAppNotFound))
+		return
+	}
+	writeJSON(w, http.StatusOK, metricsResponse{Metrics: reporter.AppMetrics(name)})
+}
+
+func onAction(w http.ResponseWriter, name string, action string) {
+	var err error
+	switch action {
+	case "restart":
+		err = manager.Restart(name)
+	case "stop":
+		err = manager.Stop(name)
+	case "start":
+		err = manager.Start(name)
+	case "hold":
+		err = manager.Hold(name)
+	case "resume":
+		err = manager.Resume(name)
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown action %s", action))
+		return
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, manager.ErrAppNotFound) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err)
+		return
+	}
+	flog.Printf("[control] %s %s\n", action, name)
+
+	report, _ := reporter.Report(name)
+	writeJSON(w, http.StatusAccepted, report)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		flog.Printf("[control] encode: %s\n", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
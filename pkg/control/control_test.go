@@ -0,0 +1,108 @@
+package control
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/xackery/overseer/pkg/reporter"
+)
+
+func TestApps(t *testing.T) {
+	reporter.SetAppState("world", reporter.AppStateRunning)
+	reporter.SetAppPID("world", 1234)
+
+	srv := httptest.NewServer(Handler(""))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := NewClient(strings.TrimPrefix(srv.URL, "http://"), "")
+	apps, err := c.Apps(ctx)
+	if err != nil {
+		t.Fatalf("apps: %s", err)
+	}
+	if len(apps) != 1 {
+		t.Fatalf("expected 1 app, got %d", len(apps))
+	}
+	if apps[0].Name != "world" || apps[0].PID != 1234 || apps[0].State != "Running" {
+		t.Fatalf("unexpected app: %+v", apps[0])
+	}
+
+	app, err := c.App(ctx, "world")
+	if err != nil {
+		t.Fatalf("app: %s", err)
+	}
+	if app.PID != 1234 {
+		t.Fatalf("expected pid 1234, got %d", app.PID)
+	}
+
+	_, err = c.App(ctx, "zone99")
+	if err == nil {
+		t.Fatalf("expected error for unknown app")
+	}
+
+	err = c.Command(ctx, "zone99", "restart")
+	if err == nil || !strings.Contains(err.Error(), "app not found") {
+		t.Fatalf("expected app not found, got %v", err)
+	}
+
+	err = c.Command(ctx, "world", "explode")
+	if err == nil || !strings.Contains(err.Error(), "unknown action") {
+		t.Fatalf("expected unknown action, got %v", err)
+	}
+}
+
+func TestGuard(t *testing.T) {
+	srv := httptest.NewServer(Handler("secret"))
+	defer srv.Close()
+
+	ctx := context.Background()
+	address := strings.TrimPrefix(srv.URL, "http://")
+	_, err := NewClient(address, "secret").Apps(ctx)
+	if err != nil {
+		t.Fatalf("apps with token: %s", err)
+	}
+	_, err = NewClient(address, "wrong").Apps(ctx)
+	if err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Fatalf("expected invalid token, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		host   string
+		header bool
+		want   int
+	}{
+		{name: "no header", host: address, want: http.StatusForbidden},
+		{name: "rebound host", host: "evil.example.com:9091", header: true, want: http.StatusForbidden},
+		{name: "localhost", host: "localhost:9091", header: true, want: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/restart-all", nil)
+		if err != nil {
+			t.Fatalf("%s: new request: %s", tt.name, err)
+		}
+		req.Host = tt.host
+		if tt.header {
+			req.Header.Set(TokenHeader, "secret")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: do: %s", tt.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Fatalf("%s: expected %d, got %d", tt.name, tt.want, resp.StatusCode)
+		}
+	}
+}
+
+func TestNewTCPNeedsToken(t *testing.T) {
+	err := New("127.0.0.1:0", "")
+	if err == nil {
+		Close()
+		t.Fatalf("expected a tcp address without a token to be refused")
+	}
+}
@@ -0,0 +1,158 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression of minute, hour, day of month, month and day of week,
+// such as 0 4 * * * for 4am every day
+type Schedule struct {
+	expr     string
+	minutes  []bool
+	hours    []bool
+	days     []bool
+	months   []bool
+	weekdays []bool
+	// isAnyDay and isAnyWeekday are true when that field is *. Like cron, when both day
+	// fields are restricted a time matching either one is enough
+	isAnyDay     bool
+	isAnyWeekday bool
+}
+
+// descriptors are shorthand expressions
+var descriptors = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// Parse reads a cron expression. Fields accept *, numbers, ranges such as 1-5, lists such as
+// 1,15 and steps such as */15. Day of week is 0-6 from sunday, 7 is also sunday
+func Parse(expr string) (*Schedule, error) {
+	fields := strings.Fields(expr)
+	descriptor, ok := descriptors[strings.ToLower(strings.TrimSpace(expr))]
+	if ok {
+		fields = strings.Fields(descriptor)
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	s := &Schedule{expr: expr}
+	var err error
+	s.minutes, err = parseField(fields[0], 0, 59)
+	if err != nil {
+		return nil, fmt.Errorf("cron %q minute: %w", expr, err)
+	}
+	s.hours, err = parseField(fields[1], 0, 23)
+	if err != nil {
+		return nil, fmt.Errorf("cron %q hour: %w", expr, err)
+	}
+	s.days, err = parseField(fields[2], 1, 31)
+	if err != nil {
+		return nil, fmt.Errorf("cron %q day of month: %w", expr, err)
+	}
+	s.months, err = parseField(fields[3], 1, 12)
+	if err != nil {
+		return nil, fmt.Errorf("cron %q month: %w", expr, err)
+	}
+	s.weekdays, err = parseField(fields[4], 0, 7)
+	if err != nil {
+		return nil, fmt.Errorf("cron %q day of week: %w", expr, err)
+	}
+	if s.weekdays[7] {
+		s.weekdays[0] = true
+	}
+	s.isAnyDay = fields[2] == "*"
+	s.isAnyWeekday = fields[4] == "*"
+	return s, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// Next returns the first time after after that matches the schedule, or zero if none
+// does within 5 years, such as 0 0 30 2 *
+func (s *Schedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+	for t.Before(end) {
+		if !s.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.isDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) isDay(t time.Time) bool {
+	isDay := s.days[t.Day()]
+	isWeekday := s.weekdays[int(t.Weekday())]
+	if s.isAnyDay || s.isAnyWeekday {
+		return isDay && isWeekday
+	}
+	return isDay || isWeekday
+}
+
+// parseField returns which values from min to max a field matches, indexed by value
+func parseField(field string, min int, max int) ([]bool, error) {
+	matches := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		rangePart, stepPart, isStep := strings.Cut(part, "/")
+		if isStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		start, end := min, max
+		if rangePart != "*" {
+			startPart, endPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			start, err = strconv.Atoi(startPart)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", startPart)
+			}
+			end = start
+			if isRange {
+				end, err = strconv.Atoi(endPart)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value %q", endPart)
+				}
+			} else if isStep {
+				// 5/15 means every 15 starting at 5
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for i := start; i <= end; i += step {
+			matches[i] = true
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,47 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// a saturday
+	from := time.Date(2026, 10, 17, 10, 30, 15, 0, time.UTC)
+	for _, tt := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 4 * * *", time.Date(2026, 10, 18, 4, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 10, 17, 10, 45, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2026, 10, 18, 10, 30, 0, 0, time.UTC)},
+		{"0 6 * * 1-5", time.Date(2026, 10, 19, 6, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 * 6", time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)},
+	} {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("parse %s: %s", tt.expr, err)
+		}
+		got := s.Next(from)
+		if !got.Equal(tt.want) {
+			t.Fatalf("%s: expected %s, got %s", tt.expr, tt.want, got)
+		}
+	}
+
+	s, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	if !s.Next(from).IsZero() {
+		t.Fatalf("expected no time for february 30th")
+	}
+
+	for _, bad := range []string{"", "0 4 * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err = Parse(bad)
+		if err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
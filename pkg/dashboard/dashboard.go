@@ -3,20 +3,25 @@ package dashboard
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/xackery/overseer/pkg/eqlog"
 	"github.com/xackery/overseer/pkg/flog"
+	"github.com/xackery/overseer/pkg/manager"
 	"github.com/xackery/overseer/pkg/reporter"
 	"github.com/xackery/overseer/pkg/signal"
-	"github.com/xackery/overseer/pkg/telnet"
 	"golang.org/x/term"
 )
 
 type Dashboard struct {
-	version       string
-	stateOrdering []string
+	version     string
+	forcedKills []string       // apps that had to be killed during shutdown
+	logFilter   eqlog.Severity // the least severe log lines shown
+	isStopping  bool           // true once quit is pressed, while apps shut down
 }
 
 // RefreshRequest is a message that tells the program to refresh the dashboard.
@@ -27,12 +32,23 @@ func (r RefreshRequest) String() string {
 	return "RefreshRequest"
 }
 
+// shutdownDone is sent once every app and worker has stopped after quitting
+type shutdownDone struct {
+	forcedKills []string
+}
+
 func New(version string) Dashboard {
 	e := Dashboard{
-		version: version,
+		version:   version,
+		logFilter: eqlog.SeverityWarning,
 	}
-	state := reporter.AppStates()
-	e.stateOrdering = []string{}
+	return e
+}
+
+// stateOrdering returns the services to list, world and ucs first, then other apps by name.
+// It is built from the current state, since apps are only reported once they first run
+func stateOrdering(states map[string]reporter.AppState) []string {
+	ordering := []string{}
 	builtinApps := []string{
 		"world",
 		"ucs",
@@ -43,12 +59,16 @@ func New(version string) Dashboard {
 		if builtin == "zone" {
 			continue
 		}
-		e.stateOrdering = append(e.stateOrdering, builtin)
+		// a builtin given a display_name is listed under that name instead
+		_, ok := states[builtin]
+		if !ok {
+			continue
+		}
+		ordering = append(ordering, builtin)
 	}
 
-	//flog.Printf("Number of services tracked: %d\n", len(e.stateOrdering))
-
-	for appName := range state.States {
+	others := []string{}
+	for appName := range states {
 		isFound := false
 		for _, builtinApp := range builtinApps {
 			if !strings.HasPrefix(appName, builtinApp) {
@@ -59,10 +79,11 @@ func New(version string) Dashboard {
 
 		}
 		if !isFound {
-			e.stateOrdering = append(e.stateOrdering, appName)
+			others = append(others, appName)
 		}
 	}
-	return e
+	sort.Strings(others)
+	return append(ordering, others...)
 }
 
 func (e Dashboard) Init() tea.Cmd {
@@ -74,15 +95,35 @@ func (e Dashboard) Init() tea.Cmd {
 func (e Dashboard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case RefreshRequest:
+	case shutdownDone:
+		e.forcedKills = msg.forcedKills
+		return e, tea.Quit
 	case tea.KeyMsg:
 		// Cool, what was the actual key pressed?
 		switch msg.String() {
 		// These keys should exit the program.
 		case "ctrl+c", "q":
+			if e.isStopping {
+				return e, nil
+			}
 			flog.Println("[dashboard] received ctrl+c or q, exiting")
-			signal.Cancel()
-			signal.WaitWorker()
-			return e, tea.Quit
+			e.isStopping = true
+			// shutting down can take until ShutdownTimeout, so the dashboard keeps drawing meanwhile
+			return e, shutdown
+		case "f":
+			// cycle info, warning, error, crash
+			e.logFilter++
+			if e.logFilter > eqlog.SeverityCrash {
+				e.logFilter = eqlog.SeverityInfo
+			}
+		case "r":
+			flog.Println("[dashboard] received r, restarting all")
+			go func() {
+				err := manager.RestartAll(signal.Ctx(), manager.ShutdownTimeout)
+				if err != nil {
+					flog.Printf("[dashboard] restart all: %s\n", err)
+				}
+			}()
 		}
 	}
 
@@ -91,6 +132,17 @@ func (e Dashboard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return e, nil
 }
 
+// shutdown stops every app and waits on workers, off the update loop
+func shutdown() tea.Msg {
+	deadline := time.Now().Add(manager.ShutdownTimeout)
+	forcedKills := manager.Shutdown(manager.ShutdownTimeout)
+	signal.Cancel()
+	if !signal.WaitWorker(time.Until(deadline)) {
+		flog.Println("[dashboard] gave up waiting on workers at shutdown deadline")
+	}
+	return shutdownDone{forcedKills: forcedKills}
+}
+
 func (e Dashboard) View() string {
 	physicalWidth, _, _ := term.GetSize(int(os.Stdout.Fd()))
 
@@ -101,24 +153,46 @@ func (e Dashboard) View() string {
 	doc := strings.Builder{}
 
 	height := 0
-	select {
-	case <-signal.Ctx().Done():
+	if e.isStopping || signal.Ctx().Err() != nil {
 		doc.WriteString(titleStyle.Width(titleWidth).Render("Shutting down..."))
-	default:
+	} else {
 		doc.WriteString(titleStyle.Width(titleWidth).Render("Overseer v" + e.version))
 	}
 	doc.WriteString("\n\n")
 	height += 2
 
 	state := reporter.AppStates()
+	world := reporter.World()
+	stats := []string{
+		listHeader("Stats"),
+		renderIcon("👤", fmt.Sprintf("%d Online", world.Online)),              // person emoji: 👤
+		renderIcon("💪", fmt.Sprintf("%d Average Level", world.AvgLevel)),     // arm emoji: 💪
+		renderIcon("🛹", fmt.Sprintf("%s Popular Class", world.PopularClass)), // board emoji: 🛹
+	}
+	nextRestart := reporter.NextRestart()
+	if !nextRestart.IsZero() {
+		stats = append(stats, renderIcon("⏰", "Restart "+restartCountdown(nextRestart))) // alarm clock emoji: ⏰
+	}
 
 	renderStates := []string{
 		listHeader("Services"),
 	}
-	for _, order := range e.stateOrdering {
+	for _, order := range stateOrdering(state.States) {
 		renderStates = append(renderStates, renderState(state.States[order], order))
 	}
 
+	staticZones := []string{}
+	for name := range state.StaticZones {
+		staticZones = append(staticZones, name)
+	}
+	sort.Strings(staticZones)
+	if len(staticZones) > 0 {
+		renderStates = append(renderStates, listHeader("Static Zones"))
+	}
+	for _, name := range staticZones {
+		renderStates = append(renderStates, renderState(state.StaticZones[name], name))
+	}
+
 	//colHeight := physicalHeight - height - 2
 	doc.WriteString(lipgloss.JoinHorizontal(
 		lipgloss.Top,
@@ -138,19 +212,172 @@ func (e Dashboard) View() string {
 				renderState(reporter.AppStateErroring, fmt.Sprintf("%d", state.ZoneErroring)),
 				renderState(reporter.AppStateRestarting, fmt.Sprintf("%d", state.ZoneRestarting)),
 				renderState(reporter.AppStateStopped, fmt.Sprintf("%d", state.ZoneStopped)),
+				renderState(reporter.AppStateHeld, fmt.Sprintf("%d", state.ZoneHeld)),
+				renderState(reporter.AppStateCrashLoop, fmt.Sprintf("%d", state.ZoneCrashLoop)),
+				renderState(reporter.AppStateHung, fmt.Sprintf("%d", state.ZoneHung)),
 			),
 		),
 		list.Copy().Width(27).Render(
-			lipgloss.JoinVertical(
-				lipgloss.Left,
-				listHeader("Stats"),
-				renderIcon("👤", fmt.Sprintf("%d Online", telnet.OnlineCount())),         // person emoji: 👤
-				renderIcon("💪", fmt.Sprintf("%d Average Level", telnet.AvgLevel())),     // arm emoji: 💪
-				renderIcon("🛹", fmt.Sprintf("%s Popular Class", telnet.PopularClass())), // board emoji: 🛹
-			),
+			lipgloss.JoinVertical(lipgloss.Left, stats...),
 		),
 	))
 	doc.WriteString("\n\n")
 
+	usage := processUsage(maxProcesses)
+	if len(usage) > 1 {
+		doc.WriteString(list.Copy().Width(titleWidth - 2).Render(
+			lipgloss.JoinVertical(lipgloss.Left, usage...),
+		))
+		doc.WriteString("\n\n")
+	}
+
+	if len(world.Players) > 0 {
+		doc.WriteString(list.Copy().Width(titleWidth - 2).Render(
+			lipgloss.JoinVertical(lipgloss.Left, playerList(world.Players, maxPlayers)...),
+		))
+		doc.WriteString("\n\n")
+	}
+
+	for _, alert := range reporter.Alerts() {
+		doc.WriteString(renderState(reporter.AppStateErroring, alert))
+		doc.WriteString("\n")
+	}
+	for _, name := range e.forcedKills {
+		doc.WriteString(renderState(reporter.AppStateErroring, name+" needed a forced kill"))
+		doc.WriteString("\n")
+	}
+	for _, exit := range recentExits(maxExits) {
+		doc.WriteString(renderState(reporter.AppStateStopped, exit))
+		doc.WriteString("\n")
+	}
+	events := eqlog.Recent("", e.logFilter, maxLogLines)
+	if len(events) > 0 {
+		doc.WriteString(listHeader(fmt.Sprintf("Log (%s and up)", e.logFilter)))
+		doc.WriteString("\n")
+	}
+	for _, ev := range events {
+		line := fmt.Sprintf("%s %s: %s", ev.Time.Format("15:04:05"), ev.App, ev.Message)
+		if titleWidth > 3 && len(line) > titleWidth {
+			line = line[:titleWidth-3] + "..."
+		}
+		doc.WriteString(renderSeverity(ev.Severity, line))
+		doc.WriteString("\n")
+	}
+	doc.WriteString(helpStyle.Render("q: quit • r: restart all • f: log filter"))
+	doc.WriteString("\n")
+
 	return doc.String()
 }
+
+const (
+	// maxExits is how many recent unclean exits are shown
+	maxExits = 5
+	// maxLogLines is how many recent log lines are shown
+	maxLogLines = 5
+	// maxProcesses is how many apps are shown in the process table, by memory use
+	maxProcesses = 10
+	// maxPlayers is how many online players are listed
+	maxPlayers = 10
+	// maxZonePopulations is how many of the busiest zone processes are listed
+	maxZonePopulations = 5
+)
+
+// processUsage renders a header and a row per sampled app, heaviest memory use first
+func processUsage(limit int) []string {
+	reports := []reporter.AppReport{}
+	for _, report := range reporter.Reports() {
+		if report.Metrics == nil || report.PID == 0 {
+			continue
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Metrics.RSS > reports[j].Metrics.RSS
+	})
+	if len(reports) > limit {
+		reports = reports[:limit]
+	}
+
+	result := []string{listHeader(fmt.Sprintf("%-20s %7s %10s %8s %6s", "Process", "CPU", "Memory", "Threads", "FDs"))}
+	for _, report := range reports {
+		m := report.Metrics
+		result = append(result, fmt.Sprintf("%-20s %6.1f%% %10s %8d %6d", report.Name, m.CPUPercent, reporter.FormatBytes(m.RSS), m.Threads, m.FDs))
+	}
+	return result
+}
+
+// restartCountdown describes when a scheduled restart is, such as in 5h12m
+func restartCountdown(at time.Time) string {
+	until := time.Until(at)
+	if until <= 0 {
+		return "in progress"
+	}
+	if until < time.Minute {
+		return fmt.Sprintf("in %s", until.Round(time.Second))
+	}
+	return fmt.Sprintf("in %s", strings.TrimSuffix(until.Truncate(time.Minute).String(), "0s"))
+}
+
+// playerList renders the busiest zone processes, then a row per online player sorted by name
+func playerList(players []reporter.Player, limit int) []string {
+	result := []string{listHeader(fmt.Sprintf("%-20s %7s  %s", "Zone Process", "Players", "Zones"))}
+	populations := reporter.ZonePopulations()
+	if len(populations) > maxZonePopulations {
+		populations = populations[:maxZonePopulations]
+	}
+	for _, pop := range populations {
+		result = append(result, fmt.Sprintf("%-20s %7d  %s", pop.App, pop.Players, pop.Zones))
+	}
+
+	result = append(result, "", listHeader(fmt.Sprintf("%-20s %5s %-13s %s", "Player", "Level", "Class", "Zone")))
+	for i, player := range players {
+		if i == limit {
+			result = append(result, fmt.Sprintf("...and %d more", len(players)-limit))
+			break
+		}
+		zone := player.Zone
+		if player.App != "" && player.App != player.Zone {
+			zone = fmt.Sprintf("%s (%s)", player.Zone, player.App)
+		}
+		result = append(result, fmt.Sprintf("%-20s %5d %-13s %s", player.Name, player.Level, player.Class, zone))
+	}
+	return result
+}
+
+// recentExits describes the most recent unclean exits, newest first
+func recentExits(limit int) []string {
+	reports := []reporter.AppReport{}
+	for _, report := range reporter.Reports() {
+		if report.LastExit == nil {
+			continue
+		}
+		if report.LastExit.Code == 0 && report.LastExit.Signal == "" {
+			continue
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].LastExit.At.After(reports[j].LastExit.At)
+	})
+	if len(reports) > limit {
+		reports = reports[:limit]
+	}
+
+	result := []string{}
+	for _, report := range reports {
+		exit := report.LastExit
+		msg := fmt.Sprintf("%s exit code %d", report.Name, exit.Code)
+		if exit.Signal != "" {
+			msg = fmt.Sprintf("%s signal %s", report.Name, exit.Signal)
+		}
+		if exit.IsCoreDumped {
+			msg += " (core dumped)"
+		}
+		if exit.IsKilled {
+			msg += " (forced kill)"
+		}
+		msg += fmt.Sprintf(" after %s, %s ago", exit.Runtime.Round(time.Second), time.Since(exit.At).Round(time.Second))
+		result = append(result, msg)
+	}
+	return result
+}
@@ -2,6 +2,7 @@ package dashboard
 
 import (
 	"github.com/charmbracelet/lipgloss"
+	"github.com/xackery/overseer/pkg/eqlog"
 	"github.com/xackery/overseer/pkg/reporter"
 )
 
@@ -23,6 +24,9 @@ var (
 		Height(8).
 		Width(27)
 
+	helpStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.AdaptiveColor{Light: "#969B86", Dark: "#696969"})
+
 	listHeader = lipgloss.NewStyle().
 			BorderStyle(lipgloss.NormalBorder()).
 			BorderBottom(true).
@@ -69,6 +73,13 @@ func renderState(state reporter.AppState, msg string) string {
 								String() + lipgloss.NewStyle().
 								Foreground(lipgloss.AdaptiveColor{Light: "#969B86", Dark: "#696969"}).
 								Render(msg) //+" Erroring")
+	case reporter.AppStateCrashLoop:
+		return lipgloss.NewStyle().SetString("💥"). //collision
+								Foreground(red).
+								PaddingRight(1).
+								String() + lipgloss.NewStyle().
+								Foreground(lipgloss.AdaptiveColor{Light: "#969B86", Dark: "#696969"}).
+								Render(msg) //+" Crash Loop")
 	case reporter.AppStateRestarting:
 		return lipgloss.NewStyle().SetString("🔄"). //refresh
 
@@ -77,6 +88,20 @@ func renderState(state reporter.AppState, msg string) string {
 								String() + lipgloss.NewStyle().
 								Foreground(lipgloss.AdaptiveColor{Light: "#969B86", Dark: "#696969"}).
 								Render(msg) //+" Restarting")
+	case reporter.AppStateHeld:
+		return lipgloss.NewStyle().SetString("⏸ "). //pause
+								Foreground(yellow).
+								PaddingRight(1).
+								String() + lipgloss.NewStyle().
+								Foreground(lipgloss.AdaptiveColor{Light: "#969B86", Dark: "#696969"}).
+								Render(msg) //+" Held")
+	case reporter.AppStateHung:
+		return lipgloss.NewStyle().SetString("⌛"). //hourglass
+								Foreground(red).
+								PaddingRight(1).
+								String() + lipgloss.NewStyle().
+								Foreground(lipgloss.AdaptiveColor{Light: "#969B86", Dark: "#696969"}).
+								Render(msg) //+" Hung")
 	default:
 		return lipgloss.NewStyle().SetString("? ").
 			Foreground(yellow).
@@ -95,3 +120,14 @@ func renderIcon(icon string, msg string) string {
 		Foreground(lipgloss.AdaptiveColor{Light: "#969B86", Dark: "#696969"}).
 		Render(msg)
 }
+
+// renderSeverity renders a log line colored by its severity
+func renderSeverity(severity eqlog.Severity, msg string) string {
+	switch severity {
+	case eqlog.SeverityCrash, eqlog.SeverityError:
+		return lipgloss.NewStyle().Foreground(red).Render(msg)
+	case eqlog.SeverityWarning:
+		return lipgloss.NewStyle().Foreground(yellow).Render(msg)
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#969B86", Dark: "#696969"}).Render(msg)
+}
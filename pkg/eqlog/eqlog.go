@@ -0,0 +1,213 @@
+package eqlog
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Severity is how serious a log line is
+type Severity int
+
+const (
+	SeverityDebug Severity = iota
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+	SeverityCrash
+)
+
+// HistorySize is how many events Recent can return
+const HistorySize = 500
+
+var (
+	mu     sync.RWMutex
+	events [HistorySize]Event
+	count  int
+	next   int
+
+	ansiPattern   = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	sourcePattern = regexp.MustCompile(`\(?([\w./\\-]+\.(?:cpp|hpp|h|c)):(\d+)\)?`)
+	timeLayouts   = []string{
+		"2006-01-02 15:04:05",
+		"01-02-2006 :: 15:04:05",
+		"01-02-2006 15:04:05",
+		"15:04:05",
+	}
+)
+
+// Event is a parsed line of EQEmu output
+type Event struct {
+	App string `json:"app"`
+	// Time is when the line was logged, or read if it has no timestamp
+	Time time.Time `json:"time"`
+	// Categories are the bracketed tags before the message, such as Zone and Error
+	Categories []string `json:"categories,omitempty"`
+	Severity   Severity `json:"severity"`
+	File       string   `json:"file,omitempty"`
+	Line       int      `json:"line,omitempty"`
+	Message    string   `json:"message"`
+	Raw        string   `json:"raw"`
+}
+
+// String returns the name of a severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityDebug:
+		return "debug"
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	case SeverityCrash:
+		return "crash"
+	}
+	return "unknown"
+}
+
+// MarshalText writes a severity as its name in json
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText reads a severity from its name in json
+func (s *Severity) UnmarshalText(data []byte) error {
+	severity, ok := ParseSeverity(string(data))
+	if !ok {
+		return fmt.Errorf("unknown severity %s", data)
+	}
+	*s = severity
+	return nil
+}
+
+// ParseSeverity returns the severity named name, such as error
+func ParseSeverity(name string) (Severity, bool) {
+	for s := SeverityDebug; s <= SeverityCrash; s++ {
+		if strings.EqualFold(s.String(), name) {
+			return s, true
+		}
+	}
+	return SeverityInfo, false
+}
+
+// Parse turns a line of output from app into an event. Lines that don't follow the
+// EQEmu format still become an info event with the whole line as the message
+func Parse(app string, line string) Event {
+	ev := Event{
+		App:      app,
+		Time:     time.Now(),
+		Severity: SeverityInfo,
+		Raw:      line,
+	}
+	rest := strings.TrimSpace(ansiPattern.ReplaceAllString(line, ""))
+
+	isFirst := true
+	for strings.HasPrefix(rest, "[") {
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			break
+		}
+		tag := strings.TrimSpace(rest[1:end])
+		rest = strings.TrimSpace(rest[end+1:])
+		// only the first tag can be a timestamp
+		if isFirst {
+			isFirst = false
+			at, ok := parseTime(tag)
+			if ok {
+				ev.Time = at
+				continue
+			}
+		}
+		ev.Categories = append(ev.Categories, tag)
+	}
+
+	isSeverity := false
+	for _, category := range ev.Categories {
+		severity, ok := categorySeverity(category)
+		if !ok {
+			continue
+		}
+		if !isSeverity || severity > ev.Severity {
+			ev.Severity = severity
+			isSeverity = true
+		}
+	}
+
+	match := sourcePattern.FindStringSubmatchIndex(rest)
+	if match != nil {
+		ev.File = rest[match[2]:match[3]]
+		ev.Line, _ = strconv.Atoi(rest[match[4]:match[5]])
+		rest = strings.TrimSpace(strings.TrimSpace(rest[:match[0]]) + " " + strings.TrimSpace(rest[match[1]:]))
+	}
+	ev.Message = rest
+	return ev
+}
+
+// parseTime reads a bracketed timestamp, a time without a date is assumed to be today
+func parseTime(tag string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		at, err := time.ParseInLocation(layout, tag, time.Local)
+		if err != nil {
+			continue
+		}
+		if layout == "15:04:05" {
+			now := time.Now()
+			at = time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), at.Second(), 0, time.Local)
+		}
+		return at, true
+	}
+	return time.Time{}, false
+}
+
+// categorySeverity returns the severity a category implies, if any
+func categorySeverity(category string) (Severity, bool) {
+	switch strings.ToLower(category) {
+	case "crash", "fatal":
+		return SeverityCrash, true
+	case "error", "mysql error", "quest errors", "lua errors":
+		return SeverityError, true
+	case "warning", "warn":
+		return SeverityWarning, true
+	case "debug", "detail":
+		return SeverityDebug, true
+	}
+	return SeverityInfo, false
+}
+
+// Record keeps an event, so the latest HistorySize can be read back with Recent
+func Record(ev Event) {
+	mu.Lock()
+	defer mu.Unlock()
+	events[next] = ev
+	next = (next + 1) % HistorySize
+	if count < HistorySize {
+		count++
+	}
+}
+
+// Recent returns up to limit of the newest recorded events at or above min, from app if
+// it is not empty, oldest first
+func Recent(app string, min Severity, limit int) []Event {
+	mu.RLock()
+	defer mu.RUnlock()
+	result := []Event{}
+	for i := 0; i < count && len(result) < limit; i++ {
+		ev := events[(next-1-i+HistorySize)%HistorySize]
+		if ev.Severity < min {
+			continue
+		}
+		if app != "" && ev.App != app {
+			continue
+		}
+		result = append(result, ev)
+	}
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
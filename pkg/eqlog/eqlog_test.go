@@ -0,0 +1,51 @@
+package eqlog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	ev := Parse("zone3", "[11-12-2023 :: 10:15:01] [Zone] [Crash] Segmentation fault (zone/entity.cpp:1234) in Entity::Process")
+	if ev.App != "zone3" || ev.Severity != SeverityCrash {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+	want := time.Date(2023, 11, 12, 10, 15, 1, 0, time.Local)
+	if !ev.Time.Equal(want) {
+		t.Fatalf("expected time %s, got %s", want, ev.Time)
+	}
+	if len(ev.Categories) != 2 || ev.Categories[0] != "Zone" {
+		t.Fatalf("unexpected categories: %v", ev.Categories)
+	}
+	if ev.File != "zone/entity.cpp" || ev.Line != 1234 {
+		t.Fatalf("unexpected source %s:%d", ev.File, ev.Line)
+	}
+	if ev.Message != "Segmentation fault in Entity::Process" {
+		t.Fatalf("unexpected message %q", ev.Message)
+	}
+
+	ev = Parse("world", "\x1b[1;31m[World] [Error] Unable to connect to database\x1b[0m")
+	if ev.Severity != SeverityError || ev.Message != "Unable to connect to database" {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+
+	ev = Parse("bot", "plain output")
+	if ev.Severity != SeverityInfo || ev.Message != "plain output" || len(ev.Categories) != 0 {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+func TestRecent(t *testing.T) {
+	Record(Parse("world", "[World] [Info] started"))
+	Record(Parse("zone1", "[Zone] [Warning] slow tick"))
+	Record(Parse("world", "[World] [Error] lost zone"))
+
+	events := Recent("", SeverityWarning, 10)
+	if len(events) != 2 || events[0].App != "zone1" || events[1].App != "world" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	events = Recent("world", SeverityInfo, 1)
+	if len(events) != 1 || events[0].Message != "lost zone" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+}
@@ -2,19 +2,18 @@ package flog
 
 import (
 	"fmt"
-	"os"
 )
 
 var (
-	w *os.File
+	w *File
 )
 
-// New creates a new file logger
-func New(path string) error {
+// New creates a new file logger, rotated as described by rotation
+func New(path string, rotation Rotation) error {
 	var err error
-	w, err = os.Create(path)
+	w, err = Open(path, rotation)
 	if err != nil {
-		return fmt.Errorf("create: %w", err)
+		return fmt.Errorf("open: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,352 @@
+package flog
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRotate is returned by Write when a rotate failed, the write itself went to the unrotated file
+var ErrRotate = errors.New("rotate")
+
+// rotateLayout is the timestamp added to a rotated file, name-20060102-150405.log
+const rotateLayout = "20060102-150405"
+
+// Rotation describes when a log file is rotated and how many rotated files are kept.
+// A zero value never rotates and truncates the file on open
+type Rotation struct {
+	// MaxSize rotates a file once it is this many bytes
+	MaxSize int64
+	// Interval rotates a file once it has been open this long
+	Interval time.Duration
+	// MaxFiles deletes the oldest rotated files past this count
+	MaxFiles int
+	// MaxAge deletes rotated files older than this
+	MaxAge time.Duration
+	// IsCompressed gzips rotated files
+	IsCompressed bool
+	// IsKeepPrevious rotates the file left by the previous run on open, instead of truncating it
+	IsKeepPrevious bool
+}
+
+// File is a log file that rotates itself as it is written
+type File struct {
+	mu       sync.Mutex
+	path     string
+	rotation Rotation
+	w        *os.File
+	size     int64
+	openedAt time.Time
+	isClosed bool
+	wg       sync.WaitGroup
+	// pruneMu keeps compressing and pruning of rotated files one at a time
+	pruneMu sync.Mutex
+}
+
+// Open creates a rotating log file at path, creating its directory if needed
+func Open(path string, rotation Rotation) (*File, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("mkdir: %w", err)
+	}
+	f := &File{path: path, rotation: rotation}
+	if rotation.IsKeepPrevious {
+		fi, err := os.Stat(path)
+		if err == nil && fi.Size() > 0 {
+			err = f.rotate(fi.ModTime())
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	err = f.create()
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write writes p to the file, rotating it first if it is too big or too old
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.isClosed {
+		return 0, os.ErrClosed
+	}
+	var rotateErr error
+	if f.w == nil {
+		// an earlier rotate left no file open
+		rotateErr = f.reopen()
+		if rotateErr != nil {
+			return 0, rotateErr
+		}
+	}
+	if f.isRotateDue(len(p)) {
+		rotateErr = f.w.Close()
+		f.w = nil
+		if rotateErr == nil {
+			rotateErr = f.rotate(time.Now())
+		}
+		if rotateErr == nil {
+			rotateErr = f.create()
+		}
+		if rotateErr != nil {
+			// keep writing to the file, the next write tries rotating it again
+			err := f.reopen()
+			if err != nil {
+				return 0, fmt.Errorf("%w, reopen: %w", rotateErr, err)
+			}
+		}
+	}
+	n, err := f.w.Write(p)
+	f.size += int64(n)
+	if err == nil && rotateErr != nil {
+		err = fmt.Errorf("%w: %w", ErrRotate, rotateErr)
+	}
+	return n, err
+}
+
+// Close closes the file, and waits for any rotated file to finish compressing
+func (f *File) Close() error {
+	f.mu.Lock()
+	var err error
+	f.isClosed = true
+	if f.w != nil {
+		err = f.w.Close()
+		f.w = nil
+	}
+	f.mu.Unlock()
+	f.wg.Wait()
+	return err
+}
+
+func (f *File) isRotateDue(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.rotation.MaxSize > 0 && f.size+int64(n) > f.rotation.MaxSize {
+		return true
+	}
+	if f.rotation.Interval > 0 && time.Since(f.openedAt) >= f.rotation.Interval {
+		return true
+	}
+	return false
+}
+
+func (f *File) create() error {
+	w, err := os.Create(f.path)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	f.w = w
+	f.size = 0
+	f.openedAt = time.Now()
+	return nil
+}
+
+// reopen appends to the file, or creates it if a rotate moved it away. The file keeps its age,
+// so a rotate that is due is tried again on the next write
+func (f *File) reopen() error {
+	w, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	fi, err := w.Stat()
+	if err != nil {
+		w.Close()
+		return fmt.Errorf("stat: %w", err)
+	}
+	f.w = w
+	f.size = fi.Size()
+	if f.size == 0 {
+		f.openedAt = time.Now()
+	}
+	return nil
+}
+
+// rotate renames the closed file to a timestamped name, then compresses and prunes old files
+func (f *File) rotate(at time.Time) error {
+	ext := filepath.Ext(f.path)
+	base := strings.TrimSuffix(f.path, ext)
+	dst := fmt.Sprintf("%s-%s%s", base, at.Format(rotateLayout), ext)
+	for i := 1; isExist(dst) || isExist(dst+".gz"); i++ {
+		dst = fmt.Sprintf("%s-%s-%d%s", base, at.Format(rotateLayout), i, ext)
+	}
+	err := os.Rename(f.path, dst)
+	if err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.pruneMu.Lock()
+		defer f.pruneMu.Unlock()
+		if f.rotation.IsCompressed {
+			err := compress(dst)
+			if err != nil {
+				Printf("[flog] compress %s: %s\n", dst, err)
+			}
+		}
+		err := f.prune()
+		if err != nil {
+			Printf("[flog] prune %s: %s\n", f.path, err)
+		}
+	}()
+	return nil
+}
+
+// Rotated returns the rotated files of the log, oldest first
+func (f *File) Rotated() ([]string, error) {
+	ext := filepath.Ext(f.path)
+	base := strings.TrimSuffix(f.path, ext)
+	pattern := base + "-*" + ext
+	plain, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("glob: %w", err)
+	}
+	compressed, err := filepath.Glob(pattern + ".gz")
+	if err != nil {
+		return nil, fmt.Errorf("glob: %w", err)
+	}
+	files := []string{}
+	for _, path := range append(plain, compressed...) {
+		// the glob also matches other logs, such as world-bot.log when rotating world.log
+		if !isRotatedName(path, base, ext) {
+			continue
+		}
+		files = append(files, path)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		stampI, countI := rotatedOrder(files[i], ext)
+		stampJ, countJ := rotatedOrder(files[j], ext)
+		if stampI != stampJ {
+			return stampI < stampJ
+		}
+		return countI < countJ
+	})
+	return files, nil
+}
+
+// isRotatedName returns true if path is base-20060102-150405ext, with an optional -N
+// counter and .gz
+func isRotatedName(path string, base string, ext string) bool {
+	name := strings.TrimSuffix(strings.TrimSuffix(path, ".gz"), ext)
+	if !strings.HasPrefix(name, base+"-") {
+		return false
+	}
+	stamp := strings.TrimPrefix(name, base+"-")
+	if len(stamp) < len(rotateLayout) {
+		return false
+	}
+	_, err := time.Parse(rotateLayout, stamp[:len(rotateLayout)])
+	if err != nil {
+		return false
+	}
+	count := stamp[len(rotateLayout):]
+	if count == "" {
+		return true
+	}
+	if !strings.HasPrefix(count, "-") {
+		return false
+	}
+	_, err = strconv.Atoi(count[1:])
+	return err == nil
+}
+
+// rotatedOrder returns the timestamp and counter of a rotated file, such as
+// world-20060102-150405-2.log.gz, ignoring .gz so a file being compressed keeps its place
+func rotatedOrder(path string, ext string) (string, int) {
+	name := strings.TrimSuffix(strings.TrimSuffix(path, ".gz"), ext)
+	idx := strings.LastIndex(name, "-")
+	if idx < 0 {
+		return name, 0
+	}
+	count, err := strconv.Atoi(name[idx+1:])
+	// the time part of the timestamp is also digits after a -, but is always 6 long
+	if err != nil || len(name[idx+1:]) == len("150405") {
+		return name, 0
+	}
+	return name[:idx], count
+}
+
+// prune deletes rotated files past MaxFiles or older than MaxAge
+func (f *File) prune() error {
+	if f.rotation.MaxFiles <= 0 && f.rotation.MaxAge <= 0 {
+		return nil
+	}
+	files, err := f.Rotated()
+	if err != nil {
+		return err
+	}
+	for i, path := range files {
+		isExpired := f.rotation.MaxFiles > 0 && len(files)-i > f.rotation.MaxFiles
+		if !isExpired && f.rotation.MaxAge > 0 {
+			fi, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			isExpired = time.Since(fi.ModTime()) > f.rotation.MaxAge
+		}
+		if !isExpired {
+			continue
+		}
+		err = os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove: %w", err)
+		}
+	}
+	return nil
+}
+
+// compress gzips path to path.gz and removes path
+func compress(path string) error {
+	r, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer r.Close()
+	fi, err := r.Stat()
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+
+	w, err := os.Create(path + ".gz")
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	gw := gzip.NewWriter(w)
+	_, err = io.Copy(gw, r)
+	if err == nil {
+		err = gw.Close()
+	}
+	if err == nil {
+		err = w.Close()
+	} else {
+		w.Close()
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return fmt.Errorf("gzip: %w", err)
+	}
+	// keep the modified time, so retention by age still applies to when the log was written
+	err = os.Chtimes(path+".gz", fi.ModTime(), fi.ModTime())
+	if err != nil {
+		return fmt.Errorf("chtimes: %w", err)
+	}
+	r.Close()
+	return os.Remove(path)
+}
+
+func isExist(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,82 @@
+package flog
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "world.log")
+	err := os.WriteFile(path, []byte("previous run\n"), 0644)
+	if err != nil {
+		t.Fatalf("write: %s", err)
+	}
+	// another app named with a hyphen, which pruning world.log must leave alone
+	other := filepath.Join(filepath.Dir(path), "world-bot-20060102-150405.log")
+	err = os.WriteFile(other, []byte("bot\n"), 0644)
+	if err != nil {
+		t.Fatalf("write: %s", err)
+	}
+
+	f, err := Open(path, Rotation{MaxSize: 10, MaxFiles: 2, IsCompressed: true, IsKeepPrevious: true})
+	if err != nil {
+		t.Fatalf("open: %s", err)
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		_, err = f.Write([]byte(line))
+		if err != nil {
+			t.Fatalf("write: %s", err)
+		}
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("close: %s", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	if string(data) != "third\n" {
+		t.Fatalf("expected only the newest line, got %q", data)
+	}
+
+	// previous run, first and second were rotated, only the newest 2 are kept
+	rotated, err := f.Rotated()
+	if err != nil {
+		t.Fatalf("rotated: %s", err)
+	}
+	if len(rotated) != 2 {
+		t.Fatalf("expected 2 rotated files, got %v", rotated)
+	}
+	contents := []string{}
+	for _, name := range rotated {
+		if !strings.HasSuffix(name, ".gz") {
+			t.Fatalf("expected %s to be compressed", name)
+		}
+		r, err := os.Open(name)
+		if err != nil {
+			t.Fatalf("open: %s", err)
+		}
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatalf("gzip: %s", err)
+		}
+		data, err := io.ReadAll(gr)
+		r.Close()
+		if err != nil {
+			t.Fatalf("read: %s", err)
+		}
+		contents = append(contents, string(data))
+	}
+	if contents[0] != "first\n" || contents[1] != "second\n" {
+		t.Fatalf("unexpected rotated contents %q", contents)
+	}
+	if !isExist(other) {
+		t.Fatalf("expected %s to survive pruning", other)
+	}
+}
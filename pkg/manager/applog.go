@@ -0,0 +1,52 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/xackery/overseer/pkg/flog"
+)
+
+// openLog opens the app's own log file in logDir, output is still parsed if it can't be opened
+func (mgr *manager) openLog() {
+	if mgr.logDir == "" {
+		return
+	}
+	path := filepath.Join(mgr.logDir, mgr.displayName+".log")
+	f, err := flog.Open(path, mgr.logRotation)
+	if err != nil {
+		flog.Printf("[mgr][%s] open log %s: %s\n", mgr.displayName, path, err)
+		return
+	}
+	mgr.logFile = f
+}
+
+// writeLog writes a line of output to the app's log file, stderr lines keep their prefix
+func (mgr *manager) writeLog(line string) {
+	if mgr.logFile == nil {
+		return
+	}
+	_, err := fmt.Fprintf(mgr.logFile, "%s %s\n", time.Now().Format("2006-01-02 15:04:05"), line)
+	if errors.Is(err, flog.ErrRotate) {
+		// the line was still written, the next write tries rotating again
+		flog.Printf("[mgr][%s] write log: %s\n", mgr.displayName, err)
+		return
+	}
+	if err != nil {
+		flog.Printf("[mgr][%s] write log: %s\n", mgr.displayName, err)
+		mgr.closeLog()
+	}
+}
+
+func (mgr *manager) closeLog() {
+	if mgr.logFile == nil {
+		return
+	}
+	err := mgr.logFile.Close()
+	if err != nil {
+		flog.Printf("[mgr][%s] close log: %s\n", mgr.displayName, err)
+	}
+	mgr.logFile = nil
+}
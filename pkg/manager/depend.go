@@ -0,0 +1,194 @@
+package manager
+
+import (
+	"strings"
+	"time"
+
+	"github.com/xackery/overseer/pkg/config"
+	"github.com/xackery/overseer/pkg/flog"
+	"github.com/xackery/overseer/pkg/reporter"
+)
+
+// waitDependencies blocks until every app in dependsOn is ready, or readyTimeout passes.
+// Returns false if a command or ctx interrupted the wait and the app should not start yet
+func (mgr *manager) waitDependencies() bool {
+	if len(mgr.dependsOn) == 0 {
+		return true
+	}
+
+	start := time.Now()
+	isLogged := false
+	for {
+		pending := pendingDependencies(mgr.dependsOn)
+		if len(pending) == 0 {
+			if isLogged {
+				flog.Printf("[mgr][%s] dependencies ready after %s\n", mgr.displayName, time.Since(start).Round(time.Millisecond))
+			}
+			return true
+		}
+		if mgr.readyTimeout > 0 && time.Since(start) > mgr.readyTimeout {
+			flog.Printf("[mgr][%s] timed out after %s waiting on %s, starting anyway\n", mgr.displayName, mgr.readyTimeout, strings.Join(pending, ", "))
+			return true
+		}
+		if !isLogged {
+			flog.Printf("[mgr][%s] waiting on %s\n", mgr.displayName, strings.Join(pending, ", "))
+			mgr.setState(reporter.AppStateStarting)
+			isLogged = true
+		}
+
+		select {
+		case <-mgr.ctx.Done():
+			return false
+		case cmd := <-mgr.cmdChan:
+			switch cmd {
+			case commandStop:
+				flog.Printf("[mgr][%s] stopping by request\n", mgr.displayName)
+				mgr.isStopped = true
+				mgr.setState(reporter.AppStateStopped)
+				return false
+			case commandRetire:
+				mgr.isRetired = true
+				return false
+			case commandHold:
+				flog.Printf("[mgr][%s] holding by request, will not start\n", mgr.displayName)
+				mgr.isHeld = true
+				mgr.setHeld(true)
+				mgr.setState(reporter.AppStateHeld)
+				return false
+			case commandStart, commandRestart:
+				flog.Printf("[mgr][%s] starting by request without waiting on %s\n", mgr.displayName, strings.Join(pending, ", "))
+				return true
+			}
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// pendingDependencies returns each dependency that is not ready yet
+func pendingDependencies(dependsOn []string) []string {
+	mu.RLock()
+	mgrs := []*manager{}
+	for _, mgr := range apps {
+		mgrs = append(mgrs, mgr)
+	}
+	mu.RUnlock()
+
+	pending := []string{}
+	for _, dep := range dependsOn {
+		isFound := false
+		isReady := true
+		for _, mgr := range mgrs {
+			if !mgr.isDependency(dep) {
+				continue
+			}
+			isFound = true
+			state, _ := reporter.State(mgr.displayName)
+			if state != reporter.AppStateRunning && state != reporter.AppStateSleeping {
+				isReady = false
+				break
+			}
+		}
+		if !isFound || !isReady {
+			pending = append(pending, dep)
+		}
+	}
+	return pending
+}
+
+// isDependency returns true if the app satisfies dep, either by name or by executable, so zone
+// matches zone0 and a static zone such as qeynos2
+func (mgr *manager) isDependency(dep string) bool {
+	dep = strings.ToLower(dep)
+	if dep == strings.ToLower(mgr.displayName) {
+		return true
+	}
+	return dep == strings.ToLower(strings.TrimSuffix(mgr.exeName, ".exe"))
+}
+
+var (
+	// ShutdownTimeout is how long Shutdown waits for every app to stop
+	ShutdownTimeout = config.DefaultShutdownTimeout
+)
+
+// Shutdown stops every managed app in reverse order, so an app is only stopped once
+// everything depending on it has stopped. It gives up waiting once timeout passes,
+// and returns the apps that had to be killed after not exiting within their stop grace
+func Shutdown(timeout time.Duration) []string {
+	flog.Printf("[mgr] shutting down\n")
+	isShuttingDown.Store(true)
+	start := time.Now()
+	stopAll(start.Add(timeout))
+
+	killed := []string{}
+	for _, name := range Names() {
+		report, ok := reporter.Report(name)
+		if !ok || report.LastExit == nil {
+			continue
+		}
+		if !report.LastExit.IsKilled || report.LastExit.At.Before(start) {
+			continue
+		}
+		killed = append(killed, name)
+	}
+	if len(killed) > 0 {
+		flog.Printf("[mgr] forced kill needed for %s\n", strings.Join(killed, ", "))
+	}
+	flog.Printf("[mgr] shutdown complete\n")
+	return killed
+}
+
+// stopAll stops every managed app in reverse order, waiting on dependents first. Returns the
+// apps still running at deadline
+func stopAll(deadline time.Time) []string {
+	mu.RLock()
+	mgrs := []*manager{}
+	for i := len(order) - 1; i >= 0; i-- {
+		mgrs = append(mgrs, apps[order[i]])
+	}
+	mu.RUnlock()
+
+	for _, mgr := range mgrs {
+		for _, other := range mgrs {
+			for _, dep := range other.dependsOn {
+				if !mgr.isDependency(dep) {
+					continue
+				}
+				waitStopped(other.displayName, deadline)
+			}
+		}
+		err := mgr.send(commandStop)
+		if err != nil {
+			flog.Printf("[mgr][%s] shutdown: %s\n", mgr.displayName, err)
+		}
+	}
+	running := []string{}
+	for _, mgr := range mgrs {
+		if !waitStopped(mgr.displayName, deadline) {
+			running = append(running, mgr.displayName)
+		}
+	}
+	return running
+}
+
+// isDown returns true if an app is not running and will not start on its own
+func isDown(state reporter.AppState) bool {
+	switch state {
+	case reporter.AppStateStopped, reporter.AppStateHeld, reporter.AppStateCrashLoop:
+		return true
+	}
+	return false
+}
+
+// waitStopped blocks until an app is no longer running, or deadline passes. Returns false
+// if it is still running
+func waitStopped(name string, deadline time.Time) bool {
+	for time.Now().Before(deadline) {
+		state, ok := reporter.State(name)
+		if !ok || isDown(state) {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	flog.Printf("[mgr][%s] still running at shutdown deadline\n", name)
+	return false
+}
@@ -0,0 +1,178 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xackery/overseer/pkg/reporter"
+)
+
+var (
+	mu   sync.RWMutex
+	apps = make(map[string]*manager)
+	// order is the display name of each app in the order it was managed
+	order []string
+	// ErrAppNotFound is returned when a command targets an app that isn't managed
+	ErrAppNotFound = errors.New("app not found")
+)
+
+type command int
+
+const (
+	commandStart command = iota
+	commandStop
+	commandRestart
+	commandHold
+	commandResume
+	commandRetire
+)
+
+// Handle controls a single managed app
+type Handle struct {
+	mgr *manager
+}
+
+// Name returns the display name of the app
+func (h *Handle) Name() string {
+	return h.mgr.displayName
+}
+
+// Start starts the app if it was stopped or held
+func (h *Handle) Start() error {
+	return h.mgr.send(commandStart)
+}
+
+// Stop stops the app, and keeps it from being respawned until started again
+func (h *Handle) Stop() error {
+	return h.mgr.send(commandStop)
+}
+
+// Restart stops the app and respawns it right away
+func (h *Handle) Restart() error {
+	return h.mgr.send(commandRestart)
+}
+
+// Hold leaves the app running, but does not respawn it once it exits
+func (h *Handle) Hold() error {
+	return h.mgr.send(commandHold)
+}
+
+// Resume undoes a hold, starting the app if it exited while held
+func (h *Handle) Resume() error {
+	return h.mgr.send(commandResume)
+}
+
+// Lookup returns the handle of a managed app
+func Lookup(name string) (*Handle, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	mgr, ok := apps[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, ErrAppNotFound)
+	}
+	return &Handle{mgr: mgr}, nil
+}
+
+// Names returns the display names of all managed apps, sorted
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := []string{}
+	for name := range apps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start starts an app that was stopped or held
+func Start(name string) error {
+	h, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+	return h.Start()
+}
+
+// Stop stops an app, and keeps it from being respawned until started again
+func Stop(name string) error {
+	h, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+	return h.Stop()
+}
+
+// Restart stops an app and respawns it right away
+func Restart(name string) error {
+	h, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+	return h.Restart()
+}
+
+// Hold leaves an app running, but does not respawn it once it exits
+func Hold(name string) error {
+	h, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+	return h.Hold()
+}
+
+// Resume undoes a hold, starting the app if it exited while held
+func Resume(name string) error {
+	h, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+	return h.Resume()
+}
+
+// Retire stops the app for good and stops managing it. A running app is only retired while
+// sleeping, so a zone that got a player in the meantime is left alone
+func (h *Handle) Retire() error {
+	return h.mgr.send(commandRetire)
+}
+
+func register(mgr *manager) error {
+	mu.Lock()
+	defer mu.Unlock()
+	_, ok := apps[mgr.displayName]
+	if ok {
+		return fmt.Errorf("%s is already managed", mgr.displayName)
+	}
+	apps[mgr.displayName] = mgr
+	order = append(order, mgr.displayName)
+	return nil
+}
+
+// unregister forgets a retired app
+func (mgr *manager) unregister() {
+	mu.Lock()
+	delete(apps, mgr.displayName)
+	for i, name := range order {
+		if name != mgr.displayName {
+			continue
+		}
+		order = append(order[:i], order[i+1:]...)
+		break
+	}
+	mu.Unlock()
+	reporter.RemoveApp(mgr.displayName)
+}
+
+func (mgr *manager) send(cmd command) error {
+	select {
+	case mgr.cmdChan <- cmd:
+	case <-mgr.ctx.Done():
+		return fmt.Errorf("%s: shutting down", mgr.displayName)
+	case <-time.After(5 * time.Second):
+		return fmt.Errorf("%s: timed out waiting for manager", mgr.displayName)
+	}
+	return nil
+}
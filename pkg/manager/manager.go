@@ -5,35 +5,109 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
+	"github.com/xackery/overseer/pkg/config"
+	"github.com/xackery/overseer/pkg/eqlog"
 	"github.com/xackery/overseer/pkg/flog"
 	"github.com/xackery/overseer/pkg/message"
+	"github.com/xackery/overseer/pkg/pidfile"
 	"github.com/xackery/overseer/pkg/reporter"
+	"github.com/xackery/overseer/pkg/rule"
 	"github.com/xackery/overseer/pkg/runner"
 	"github.com/xackery/overseer/pkg/signal"
 )
 
+var (
+	dockerClient  *client.Client
+	dockerNetwork string
+	// newRunner creates the runner for an app, replaced in tests with a fake
+	newRunner = defaultRunner
+)
+
 type manager struct {
-	ctx           context.Context
-	displayName   string
-	wdPath        string
-	exePath       string
-	exeName       string
-	args          []string
-	startDelay    time.Duration
-	lastStartTime time.Time
-	state         reporter.AppState
-	restartCount  int
-	lastError     string
-	lastErrorAt   time.Time // When lastErrorAt hits 30 minutes, reset errorCount
-	errorCooldown time.Time
-	errorCount    int // When errorCount hits 3, set errorCooldown to 30 minutes
-	doneChan      chan error
-	outChan       chan string
-	isOverseerLog bool // false if config is not set
+	ctx         context.Context
+	setup       SetupType
+	image       string
+	displayName string
+	wdPath      string
+	exePath     string
+	exeName     string
+	args        []string
+	env         []string
+	rules       []*rule.Rule // log line rules, the app's own before the built in ones
+	policy      config.RestartPolicy
+	// watchdogPolicy decides when a running app is hung
+	watchdogPolicy   config.WatchdogPolicy
+	recyclePolicy    config.RecyclePolicy
+	dependsOn        []string
+	readyTimeout     time.Duration
+	stopGrace        time.Duration
+	adoptPID         int         // pid left running by a previous overseer, watched instead of spawning on first start
+	restarts         []time.Time // when each restart within policy.Window happened
+	failures         int         // consecutive crashes, used for backoff
+	lastStartTime    time.Time
+	state            reporter.AppState
+	restartCount     int
+	lastError        string
+	lastErrorAt      time.Time // When lastErrorAt hits 30 minutes, reset errorCount
+	errorCount       int       // When errorCount hits 10, set state to erroring
+	doneChan         chan error
+	outChan          chan string
+	cmdChan          chan command
+	hangChan         chan string // watchdog reports, an empty reason means recovered
+	watchdogInterval time.Duration
+	recycleChan      chan string // recycler reports, the reason a sleeping app should be restarted
+	recycleInterval  time.Duration
+	lastOutputAt     atomic.Int64 // unix nanoseconds of the last line read, used by the watchdog
+	isHung           bool         // true once the watchdog flagged the app, cleared when it exits or recovers
+	isStopped        bool         // true when stopped by a command, and should not respawn
+	isHeld           bool         // true when put on hold, and should not respawn once it exits
+	isRestarting     bool         // true when restarted by a command, and should respawn without delay
+	isRetired        bool         // true when retired, and should stop being managed once it exits
+	isOverseerLog    bool         // false if config is not set
+	logDir           string       // where the app's log file is written, empty disables it
+	logRotation      flog.Rotation
+	logFile          *flog.File
+}
+
+// AppSpec describes an app to manage
+type AppSpec struct {
+	DisplayName string
+	IsLogged    bool
+	WdPath      string
+	ExePath     string
+	ExeName     string
+	Args        []string
+	// Env are KEY=VALUE pairs added to the app's environment
+	Env []string
+	// ReadyMatch marks the app running once a line of its output contains it
+	ReadyMatch string
+	// Rules are log line rules checked before the built in ones
+	Rules  []*rule.Rule
+	Policy config.RestartPolicy
+	// Watchdog decides when the app is considered hung
+	Watchdog config.WatchdogPolicy
+	// Recycle decides when a sleeping zone is restarted to reclaim memory
+	Recycle config.RecyclePolicy
+	// DependsOn lists apps, or base names like zone, that must be running before this app starts
+	DependsOn []string
+	// ReadyTimeout is how long to wait on DependsOn before starting anyway, 0 waits forever
+	ReadyTimeout time.Duration
+	// Image is the docker image to run the app in, used by SetupDocker
+	Image string
+	// StopGrace is how long the app has to exit after being interrupted before it is killed
+	StopGrace time.Duration
+	// AdoptPID is a pid left running by a previous overseer, watched until it exits instead of starting a new copy
+	AdoptPID int
+	// Zone is the short name of the static zone this app serves, empty for other apps
+	Zone string
+	// LogDir is where the app's output is written as DisplayName.log, empty disables it
+	LogDir      string
+	LogRotation flog.Rotation
 }
 
 type SetupType int
@@ -52,18 +126,94 @@ func (e *manager) setPID(pid int) {
 	reporter.SetAppPID(e.displayName, pid)
 }
 
-// Manage is the main loop for the zone.
-func Manage(setup SetupType, displayName string, isLogged bool, wdPath string, exePath string, exeName string, args ...string) error {
-	fi, err := os.Stat(exePath + "/" + exeName)
+func (e *manager) setID(id string) {
+	reporter.SetAppID(e.displayName, id)
+}
+
+func (e *manager) setHeld(isHeld bool) {
+	reporter.SetAppHeld(e.displayName, isHeld)
+}
+
+func (e *manager) setExit(exit runner.ExitInfo) {
+	reporter.SetAppExit(e.displayName, reporter.AppExit{
+		Code:         exit.Code,
+		Signal:       exit.Signal,
+		IsCoreDumped: exit.IsCoreDumped,
+		IsKilled:     exit.IsKilled,
+		Runtime:      exit.Runtime,
+		At:           time.Now(),
+	})
+}
+
+// appRules returns the rules of an app, followed by its ready match and the built in rules
+func appRules(spec AppSpec) []*rule.Rule {
+	rules := append([]*rule.Rule{}, spec.Rules...)
+	if spec.ReadyMatch != "" {
+		rules = append(rules, rule.Ready(spec.ReadyMatch))
+	}
+	return append(rules, rule.Defaults()...)
+}
+
+// Manage starts and keeps an app running, the returned handle can control it
+func Manage(setup SetupType, spec AppSpec) (*Handle, error) {
+	fi, err := os.Stat(spec.ExePath + "/" + spec.ExeName)
 	if err != nil {
-		return fmt.Errorf("stat %s: %w", exePath+"/"+exeName, err)
+		return nil, fmt.Errorf("stat %s: %w", spec.ExePath+"/"+spec.ExeName, err)
 	}
 	if fi.IsDir() {
-		return fmt.Errorf("%s is a directory", exeName)
+		return nil, fmt.Errorf("%s is a directory", spec.ExeName)
 	}
 
-	go poll(displayName, wdPath, exePath, isLogged, exeName, args...)
-	return nil
+	if setup == SetupDocker {
+		if dockerClient == nil {
+			return nil, fmt.Errorf("docker is not initialized")
+		}
+		if spec.Image == "" {
+			return nil, fmt.Errorf("docker image is empty")
+		}
+	}
+
+	mgr := &manager{
+		ctx:              signal.Ctx(),
+		setup:            setup,
+		image:            spec.Image,
+		displayName:      spec.DisplayName,
+		wdPath:           spec.WdPath,
+		exePath:          spec.ExePath,
+		exeName:          spec.ExeName,
+		args:             spec.Args,
+		env:              spec.Env,
+		rules:            appRules(spec),
+		policy:           spec.Policy,
+		watchdogPolicy:   spec.Watchdog,
+		hangChan:         make(chan string),
+		watchdogInterval: watchdogInterval,
+		recyclePolicy:    spec.Recycle,
+		recycleChan:      make(chan string),
+		recycleInterval:  recycleInterval,
+		dependsOn:        spec.DependsOn,
+		readyTimeout:     spec.ReadyTimeout,
+		stopGrace:        spec.StopGrace,
+		adoptPID:         spec.AdoptPID,
+		outChan:          make(chan string),
+		cmdChan:          make(chan command),
+		lastError:        "none",
+		doneChan:         make(chan error, 1),
+		isOverseerLog:    spec.IsLogged,
+		logDir:           spec.LogDir,
+		logRotation:      spec.LogRotation,
+	}
+
+	err = register(mgr)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Zone != "" {
+		reporter.SetAppZone(mgr.displayName, spec.Zone)
+	}
+
+	go poll(mgr)
+	return &Handle{mgr: mgr}, nil
 }
 
 func InitializeDockerNetwork(networkName string) error {
@@ -86,6 +236,8 @@ func InitializeDockerNetwork(networkName string) error {
 		return fmt.Errorf("network list: %w", err)
 	}
 
+	dockerClient = cli
+	dockerNetwork = networkName
 	for _, network := range networks {
 		if network.Name == networkName {
 			return nil
@@ -102,71 +254,210 @@ func InitializeDockerNetwork(networkName string) error {
 	return nil
 }
 
-func poll(displayName string, wdPath string, exePath string, isLogged bool, exeName string, args ...string) {
+// defaultRunner picks a runner based on the setup type
+func defaultRunner(mgr *manager) runner.Runner {
+	switch mgr.setup {
+	case SetupDocker:
+		run := runner.NewDocker(dockerClient, mgr.outChan, mgr.displayName, mgr.image, dockerNetwork, mgr.wdPath, mgr.exePath, mgr.exeName, mgr.args...)
+		run.SetEnv(mgr.env)
+		return run
+	default:
+		run := runner.NewProcess(mgr.outChan, mgr.displayName, mgr.wdPath, mgr.exePath, mgr.exeName, mgr.args...)
+		run.SetEnv(mgr.env)
+		return run
+	}
+}
+
+func poll(mgr *manager) {
 	signal.AddWorker()
 	defer signal.FinishWorker()
 
-	mgr := &manager{
-		ctx:         signal.Ctx(),
-		displayName: displayName,
-		wdPath:      wdPath,
-		exePath:     exePath,
-		exeName:     exeName,
-		args:        args,
-		outChan:     make(chan string),
-		lastError:   "none",
-		doneChan:    make(chan error),
-	}
-
-	run := runner.NewProcess(mgr.outChan, mgr.doneChan, mgr.displayName, mgr.wdPath, mgr.exePath, mgr.exeName, mgr.args...)
+	mgr.openLog()
+	defer mgr.closeLog()
+
+	spawner := newRunner(mgr)
+	run := spawner
 	for {
 		select {
 		case <-mgr.ctx.Done():
 			flog.Printf("[mgr][%s] exiting: ctx done\n", mgr.displayName)
 			mgr.setState(reporter.AppStateStopped)
-			run.Stop()
+			mgr.drainStop(run)
 			return
 		default:
 		}
+		if mgr.isRetired {
+			flog.Printf("[mgr][%s] retired\n", mgr.displayName)
+			mgr.unregister()
+			return
+		}
+		if (mgr.isStopped || mgr.isHeld) && !mgr.waitStart() {
+			if mgr.isRetired {
+				flog.Printf("[mgr][%s] retired\n", mgr.displayName)
+				mgr.unregister()
+				return
+			}
+			flog.Printf("[mgr][%s] exiting: ctx done\n", mgr.displayName)
+			return
+		}
+		run = spawner
+		isAdopted := false
+		if mgr.adoptPID != 0 {
+			// a process left by a previous overseer is already past its dependencies
+			run = runner.NewAdopted(mgr.displayName, mgr.adoptPID)
+			mgr.adoptPID = 0
+			isAdopted = true
+		} else if !mgr.waitDependencies() {
+			continue
+		}
 		mgr.lastStartTime = time.Now()
-		go run.Start(mgr.ctx)
 		mgr.setState(reporter.AppStateStarting)
+		err := run.Start(mgr.ctx)
+		if err != nil {
+			flog.Printf("[mgr][%s] start: %s\n", mgr.displayName, err)
+			go func() {
+				mgr.doneChan <- err
+			}()
+		} else {
+			if isAdopted {
+				mgr.setState(reporter.AppStateRunning)
+			}
+			if run.PID() != 0 {
+				err = pidfile.Set(mgr.displayName, run.PID())
+				if err != nil {
+					flog.Printf("[mgr][%s] pidfile: %s\n", mgr.displayName, err)
+				}
+			}
+			go func(run runner.Runner) {
+				mgr.doneChan <- run.Wait()
+			}(run)
+		}
 		mgr.setPID(run.PID())
+		mgr.setID(run.ID())
+		mgr.lastOutputAt.Store(time.Now().UnixNano())
 
+		watchdogCtx, watchdogCancel := context.WithCancel(mgr.ctx)
+		if err == nil {
+			go mgr.watchdog(watchdogCtx, run.PID())
+			go mgr.recycler(watchdogCtx, mgr.lastStartTime)
+		}
 		parse(mgr, run)
+		watchdogCancel()
 	}
 }
 
-func parse(mgr *manager, run *runner.ProcessRunner) {
+// waitStart blocks while an app is stopped or held, returns false if ctx is done
+func (mgr *manager) waitStart() bool {
+	for {
+		select {
+		case <-mgr.ctx.Done():
+			return false
+		case cmd := <-mgr.cmdChan:
+			switch cmd {
+			case commandStop:
+				flog.Printf("[mgr][%s] already stopped\n", mgr.displayName)
+				continue
+			case commandRetire:
+				mgr.isRetired = true
+				return false
+			case commandHold:
+				flog.Printf("[mgr][%s] already not running, holding\n", mgr.displayName)
+				mgr.isHeld = true
+				mgr.setHeld(true)
+				continue
+			case commandResume:
+				mgr.isHeld = false
+				mgr.setHeld(false)
+				if mgr.isStopped {
+					flog.Printf("[mgr][%s] resumed, but still stopped\n", mgr.displayName)
+					continue
+				}
+			}
+			flog.Printf("[mgr][%s] starting by request\n", mgr.displayName)
+			if mgr.state == reporter.AppStateCrashLoop {
+				mgr.clearCrashLoop()
+			}
+			mgr.isStopped = false
+			mgr.isHeld = false
+			mgr.setHeld(false)
+			return true
+		}
+	}
+}
+
+func parse(mgr *manager, run runner.Runner) {
 	start := time.Now()
 	for {
 		mgr.setPID(run.PID())
+		mgr.setID(run.ID())
 		select {
 		case line := <-mgr.outChan:
+			mgr.lastOutputAt.Store(time.Now().UnixNano())
 			//if !mgr.isOverseerLog {
 			//	return
 			//}
 			mgr.lineParse(line)
+		case cmd := <-mgr.cmdChan:
+			mgr.onCommand(cmd, run)
+		case reason := <-mgr.hangChan:
+			mgr.onHang(reason, run)
+		case reason := <-mgr.recycleChan:
+			mgr.onRecycle(reason, run)
 		case <-mgr.ctx.Done():
 			flog.Printf("[mgr][%s] exiting parser: ctx done\n", mgr.displayName)
 			return
-		case <-mgr.doneChan:
-			mgr.restartCount++
-
-			flog.Printf("[mgr][%s] exited after %s seconds, %d restarts. Last error: %s\n", mgr.displayName, time.Since(start).Round(time.Second), mgr.restartCount, mgr.lastError)
-			if time.Since(start) > 3*time.Minute {
-				mgr.startDelay = 0
+		case exitErr := <-mgr.doneChan:
+			mgr.setPID(0)
+			mgr.setID("")
+			exit := run.ExitInfo()
+			mgr.setExit(exit)
+			err := pidfile.Remove(mgr.displayName)
+			if err != nil {
+				flog.Printf("[mgr][%s] pidfile: %s\n", mgr.displayName, err)
+			}
+			if mgr.isHung {
+				// a hung app stopped by the watchdog counts as a failure, even if it exited cleanly
+				if exitErr == nil {
+					exitErr = fmt.Errorf("hung")
+				}
+				mgr.isHung = false
+				reporter.SetAppAlert(mgr.displayName, "")
+			}
+			if exit.IsKilled {
+				flog.Printf("[mgr][%s] needed a forced kill after not exiting within %s\n", mgr.displayName, mgr.stopGrace)
+			}
+			if mgr.isStopped {
+				flog.Printf("[mgr][%s] stopped after %s by request\n", mgr.displayName, time.Since(start).Round(time.Second))
+				mgr.setState(reporter.AppStateStopped)
+				return
 			}
-			mgr.startDelay += 10000 * time.Millisecond
-			if mgr.startDelay > 60000*time.Millisecond {
-				mgr.startDelay = 10000 * time.Millisecond
+			if mgr.isHeld {
+				flog.Printf("[mgr][%s] exited after %s while held, not respawning\n", mgr.displayName, time.Since(start).Round(time.Second))
+				mgr.setState(reporter.AppStateHeld)
+				return
+			}
+			if mgr.isRestarting {
+				mgr.restartCount++
+				reporter.SetAppRestartCount(mgr.displayName, mgr.restartCount)
+				flog.Printf("[mgr][%s] restarted after %s by request, %d restarts\n", mgr.displayName, time.Since(start).Round(time.Second), mgr.restartCount)
+				mgr.isRestarting = false
+				mgr.setState(reporter.AppStateRestarting)
+				return
+			}
+
+			uptime := time.Since(start)
+			flog.Printf("[mgr][%s] exited with %s, %d restarts. Last error: %s\n", mgr.displayName, exit, mgr.restartCount, mgr.lastError)
+			delay, ok := mgr.onExit(exitErr, uptime)
+			if !ok {
+				return
 			}
-			flog.Printf("[mgr][%s] restarting in %s\n", mgr.displayName, mgr.startDelay)
+			mgr.restartCount++
+			reporter.SetAppRestartCount(mgr.displayName, mgr.restartCount)
+			flog.Printf("[mgr][%s] restarting in %s\n", mgr.displayName, delay.Round(time.Millisecond))
 			mgr.lastError = ""
 			mgr.setState(reporter.AppStateRestarting)
-			mgr.errorCooldown = time.Now().Add(30 * time.Minute)
 			mgr.errorCount = 0
-			time.Sleep(mgr.startDelay)
+			mgr.wait(delay)
 			return
 		case <-time.After(10 * time.Second):
 			if time.Since(mgr.lastStartTime) > 10*time.Second && mgr.state == reporter.AppStateStarting {
@@ -176,42 +467,154 @@ func parse(mgr *manager, run *runner.ProcessRunner) {
 	}
 }
 
-func (mgr *manager) lineParse(line string) {
-	if strings.Contains(line, "[Error]") {
-		mgr.lastError = line
-		mgr.lastErrorAt = time.Now()
-		flog.Printf("[%s] error: %s\n", mgr.displayName, line)
-		mgr.errorCount++
-		if mgr.errorCount >= 10 || mgr.state == reporter.AppStateStarting {
-			mgr.errorCooldown = time.Now().Add(30 * time.Minute)
-			mgr.setState(reporter.AppStateErroring)
+// onCommand handles a command while the app is running
+func (mgr *manager) onCommand(cmd command, run runner.Runner) {
+	switch cmd {
+	case commandStart:
+		if mgr.isStopped && !mgr.isRetired {
+			// a start while stopping, such as an aborted restart all, respawns once it exits
+			flog.Printf("[mgr][%s] starting again once stopped\n", mgr.displayName)
+			mgr.isStopped = false
+			mgr.isRestarting = true
+			return
 		}
-		return
+		flog.Printf("[mgr][%s] already running\n", mgr.displayName)
+	case commandStop:
+		flog.Printf("[mgr][%s] stopping by request\n", mgr.displayName)
+		mgr.isStopped = true
+		go mgr.stop(run)
+	case commandRestart:
+		flog.Printf("[mgr][%s] restarting by request\n", mgr.displayName)
+		mgr.isRestarting = true
+		go mgr.stop(run)
+	case commandHold:
+		flog.Printf("[mgr][%s] holding by request, will not respawn\n", mgr.displayName)
+		mgr.isHeld = true
+		mgr.setHeld(true)
+	case commandResume:
+		flog.Printf("[mgr][%s] resuming by request\n", mgr.displayName)
+		mgr.isHeld = false
+		mgr.setHeld(false)
+	case commandRetire:
+		// a zone picked to retire may have gotten a player since, and must keep running
+		if mgr.state != reporter.AppStateSleeping {
+			flog.Printf("[mgr][%s] skipping retire, no longer sleeping\n", mgr.displayName)
+			return
+		}
+		flog.Printf("[mgr][%s] retiring\n", mgr.displayName)
+		mgr.isRetired = true
+		mgr.isStopped = true
+		go mgr.stop(run)
 	}
-	flog.Printf("[%s] line: %s\n", mgr.displayName, line)
+}
 
-	if strings.Contains(mgr.exeName, "zone") && strings.Contains(line, "Entering sleep mode") {
-		flog.Printf("[%s] entering sleep mode\n", mgr.displayName)
-		mgr.setState(reporter.AppStateSleeping)
-		return
+// stop asks a runner to stop, parse keeps reading output meanwhile so the app can exit
+func (mgr *manager) stop(run runner.Runner) {
+	err := run.Stop(mgr.stopGrace)
+	if err != nil {
+		flog.Printf("[mgr][%s] stop: %s\n", mgr.displayName, err)
 	}
+}
 
-	if strings.Contains(mgr.exeName, "zone") &&
-		mgr.state == reporter.AppStateSleeping &&
-		strings.Contains(line, "Zone booted successfully") {
-		flog.Printf("[%s] booted successfully\n", mgr.displayName)
-		mgr.setState(reporter.AppStateRunning)
-		return
+// drainStop stops a runner while discarding its output, used once parse is no longer reading
+func (mgr *manager) drainStop(run runner.Runner) {
+	stopped := make(chan struct{})
+	go func() {
+		mgr.stop(run)
+		close(stopped)
+	}()
+	for {
+		select {
+		case <-mgr.outChan:
+		case <-stopped:
+			return
+		}
 	}
+}
 
-	if mgr.exeName == "world" && strings.Contains(line, "Starting EQ Network server on") {
-		flog.Printf("[%s] got 'Starting EQ Network server on'\n", mgr.displayName)
-		mgr.setState(reporter.AppStateRunning)
+// wait sleeps for a restart delay, a command may cut it short
+func (mgr *manager) wait(delay time.Duration) {
+	select {
+	case <-mgr.ctx.Done():
+	case cmd := <-mgr.cmdChan:
+		switch cmd {
+		case commandStop:
+			flog.Printf("[mgr][%s] stopping by request\n", mgr.displayName)
+			mgr.isStopped = true
+			mgr.setState(reporter.AppStateStopped)
+		case commandRetire:
+			mgr.isRetired = true
+		case commandHold:
+			flog.Printf("[mgr][%s] holding by request, will not respawn\n", mgr.displayName)
+			mgr.isHeld = true
+			mgr.setHeld(true)
+			mgr.setState(reporter.AppStateHeld)
+		default:
+			flog.Printf("[mgr][%s] skipping restart delay by request\n", mgr.displayName)
+		}
+	case <-time.After(delay):
+	}
+}
+
+func (mgr *manager) lineParse(line string) {
+	mgr.writeLog(line)
+	// stderr lines go through the same rules, go apps using the log package write everything there
+	source := "line"
+	if strings.HasPrefix(line, runner.StderrPrefix) {
+		line = strings.TrimPrefix(line, runner.StderrPrefix)
+		source = "stderr"
+	}
+
+	ev := eqlog.Parse(mgr.displayName, line)
+	eqlog.Record(ev)
+
+	matches := rule.Matches(mgr.rules, strings.TrimSuffix(mgr.exeName, ".exe"), mgr.state, line)
+	// lines logged as errors or crashes count as errors even without an error rule
+	isError := ev.Severity >= eqlog.SeverityError
+	for _, r := range matches {
+		if r.Action == rule.ActionError {
+			isError = true
+		}
+	}
+	if isError {
+		flog.Printf("[%s] %s: %s\n", mgr.displayName, ev.Severity, line)
+		if time.Since(mgr.lastErrorAt) > 30*time.Minute {
+			mgr.errorCount = 0
+		}
+		mgr.lastError = line
+		mgr.lastErrorAt = time.Now()
+		reporter.SetAppLastError(mgr.displayName, line)
+		mgr.errorCount++
+	} else {
+		flog.Printf("[%s] %s: %s\n", mgr.displayName, source, line)
+	}
+
+	isStateSet := false
+	for _, r := range matches {
+		switch r.Action {
+		case rule.ActionState:
+			// the first matching state rule wins, so an app's own rules override the built in ones
+			if isStateSet {
+				continue
+			}
+			isStateSet = true
+			flog.Printf("[%s] matched '%s', now %s\n", mgr.displayName, r.Pattern, reporter.AppStateString(r.State))
+			mgr.setState(r.State)
+		case rule.ActionCounter:
+			reporter.AddAppCounter(mgr.displayName, r.Name)
+		case rule.ActionEvent:
+			flog.Printf("[%s] event %s\n", mgr.displayName, r.Name)
+			reporter.AddAppEvent(mgr.displayName, reporter.AppEvent{Name: r.Name, Line: line, At: time.Now()})
+		case rule.ActionAlert:
+			flog.Printf("[%s] alert %s\n", mgr.displayName, r.Name)
+			reporter.SetAppAlert(mgr.displayName, r.Name)
+		}
+	}
+	if isError && !isStateSet && (mgr.errorCount >= 10 || mgr.state == reporter.AppStateStarting) {
+		mgr.setState(reporter.AppStateErroring)
 		return
 	}
-	if mgr.exeName == "ucs" && strings.Contains(line, "Connected to World") {
-		flog.Printf("[%s] Connected to World\n", mgr.displayName)
-		mgr.setState(reporter.AppStateRunning)
+	if isStateSet || isError {
 		return
 	}
 
@@ -219,5 +622,4 @@ func (mgr *manager) lineParse(line string) {
 		mgr.setState(reporter.AppStateRunning)
 		return
 	}
-
 }
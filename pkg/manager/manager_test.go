@@ -0,0 +1,375 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xackery/overseer/pkg/config"
+	"github.com/xackery/overseer/pkg/reporter"
+	"github.com/xackery/overseer/pkg/runner"
+)
+
+// fakeRunner is a runner that never launches anything, exits are triggered by the test
+type fakeRunner struct {
+	outChan  chan string
+	mu       sync.Mutex
+	starts   int
+	exitChan chan error
+	exitCode int
+	isStuck  bool // ignores Stop, like an app that won't exit
+}
+
+func (r *fakeRunner) Start(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.starts++
+	r.exitChan = make(chan error, 1)
+	r.exitCode = -1
+	return nil
+}
+
+func (r *fakeRunner) Wait() error {
+	r.mu.Lock()
+	exitChan := r.exitChan
+	r.mu.Unlock()
+	err := <-exitChan
+	r.mu.Lock()
+	r.exitCode = 0
+	if err != nil {
+		r.exitCode = 1
+	}
+	r.mu.Unlock()
+	return err
+}
+
+func (r *fakeRunner) Stop(grace time.Duration) error {
+	r.mu.Lock()
+	isStuck := r.isStuck
+	r.mu.Unlock()
+	if isStuck {
+		return nil
+	}
+	r.exit(nil)
+	return nil
+}
+
+// exit makes a running Wait return err
+func (r *fakeRunner) exit(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	select {
+	case r.exitChan <- err:
+	default:
+	}
+}
+
+func (r *fakeRunner) PID() int {
+	return 1234
+}
+
+func (r *fakeRunner) ID() string {
+	return "1234"
+}
+
+func (r *fakeRunner) ExitCode() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.exitCode
+}
+
+func (r *fakeRunner) ExitInfo() runner.ExitInfo {
+	return runner.ExitInfo{Code: r.ExitCode()}
+}
+
+func (r *fakeRunner) startCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.starts
+}
+
+var drainOnce sync.Once
+
+// manageFake manages an app backed by a fakeRunner, with a short restart backoff
+func manageFake(t *testing.T, spec AppSpec) (*Handle, *fakeRunner) {
+	t.Helper()
+	drainOnce.Do(func() {
+		go func() {
+			for range reporter.SendUpdateChan {
+			}
+		}()
+	})
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, spec.ExeName), []byte{}, 0755)
+	if err != nil {
+		t.Fatalf("write exe: %s", err)
+	}
+	spec.WdPath = dir
+	spec.ExePath = dir
+
+	fakeChan := make(chan *fakeRunner, 1)
+	newRunner = func(mgr *manager) runner.Runner {
+		fake := &fakeRunner{outChan: mgr.outChan}
+		fakeChan <- fake
+		return fake
+	}
+	t.Cleanup(func() {
+		newRunner = defaultRunner
+		h, err := Lookup(spec.DisplayName)
+		if err == nil {
+			h.Stop()
+		}
+		mu.Lock()
+		delete(apps, spec.DisplayName)
+		order = order[:0]
+		for name := range apps {
+			order = append(order, name)
+		}
+		mu.Unlock()
+	})
+
+	spec.Policy = config.DefaultRestartPolicy()
+	spec.Policy.Backoff = 10 * time.Millisecond
+	spec.Policy.BackoffMax = 10 * time.Millisecond
+	spec.Policy.Jitter = 0
+
+	h, err := Manage(SetupDefault, spec)
+	if err != nil {
+		t.Fatalf("manage: %s", err)
+	}
+	return h, <-fakeChan
+}
+
+func TestManageFakeRunner(t *testing.T) {
+	h, fake := manageFake(t, AppSpec{
+		DisplayName: "fakeworld",
+		ExeName:     "world",
+	})
+
+	waitState(t, "fakeworld", reporter.AppStateStarting)
+	if fake.startCount() != 1 {
+		t.Fatalf("expected 1 start, got %d", fake.startCount())
+	}
+
+	fake.outChan <- "Starting EQ Network server on port 9000"
+	waitState(t, "fakeworld", reporter.AppStateRunning)
+
+	fake.exit(fmt.Errorf("wait: exit status 1"))
+	waitFor(t, "respawn", func() bool {
+		return fake.startCount() == 2
+	})
+	report, _ := reporter.Report("fakeworld")
+	if report.LastExit == nil || report.LastExit.Code != 1 {
+		t.Fatalf("expected last exit code 1, got %+v", report.LastExit)
+	}
+
+	err := h.Stop()
+	if err != nil {
+		t.Fatalf("stop: %s", err)
+	}
+	waitState(t, "fakeworld", reporter.AppStateStopped)
+	time.Sleep(50 * time.Millisecond)
+	if fake.startCount() != 2 {
+		t.Fatalf("expected no respawn after stop, got %d starts", fake.startCount())
+	}
+	if fake.ExitCode() != 0 {
+		t.Fatalf("expected exit code 0 after stop, got %d", fake.ExitCode())
+	}
+}
+
+func TestStderrReadyMatch(t *testing.T) {
+	_, fake := manageFake(t, AppSpec{
+		DisplayName: "stderrbot",
+		ExeName:     "bot",
+		ReadyMatch:  "connected",
+	})
+
+	waitState(t, "stderrbot", reporter.AppStateStarting)
+	fake.outChan <- runner.StderrPrefix + "2024/01/01 00:00:00 connected"
+	waitState(t, "stderrbot", reporter.AppStateRunning)
+	report, _ := reporter.Report("stderrbot")
+	if report.LastError != "" {
+		t.Fatalf("expected a plain stderr line not to be an error, got %q", report.LastError)
+	}
+
+	fake.outChan <- runner.StderrPrefix + "[Error] lost connection"
+	waitFor(t, "last error", func() bool {
+		report, _ := reporter.Report("stderrbot")
+		return report.LastError == "[Error] lost connection"
+	})
+}
+
+func waitState(t *testing.T, name string, want reporter.AppState) {
+	t.Helper()
+	waitFor(t, reporter.AppStateString(want), func() bool {
+		state, _ := reporter.State(name)
+		return state == want
+	})
+}
+
+func waitFor(t *testing.T, what string, fn func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if fn() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestWatchdogHungApp(t *testing.T) {
+	watchdogInterval = 10 * time.Millisecond
+	defer func() {
+		watchdogInterval = 5 * time.Second
+	}()
+
+	_, fake := manageFake(t, AppSpec{
+		DisplayName: "hungworld",
+		ExeName:     "world",
+		Watchdog: config.WatchdogPolicy{
+			OutputTimeout: 100 * time.Millisecond,
+		},
+	})
+
+	fake.outChan <- "Starting EQ Network server on port 9000"
+	waitState(t, "hungworld", reporter.AppStateRunning)
+	waitState(t, "hungworld", reporter.AppStateHung)
+	report, _ := reporter.Report("hungworld")
+	if !strings.HasPrefix(report.Alert, "hung, no output for") {
+		t.Fatalf("expected hang alert, got %q", report.Alert)
+	}
+
+	fake.outChan <- "still alive"
+	waitState(t, "hungworld", reporter.AppStateRunning)
+	report, _ = reporter.Report("hungworld")
+	if report.Alert != "" {
+		t.Fatalf("expected hang alert to clear on recovery, got %q", report.Alert)
+	}
+	if fake.startCount() != 1 {
+		t.Fatalf("expected no restart without watchdog_restart, got %d starts", fake.startCount())
+	}
+
+	_, fake = manageFake(t, AppSpec{
+		DisplayName: "hungucs",
+		ExeName:     "ucs",
+		Watchdog: config.WatchdogPolicy{
+			OutputTimeout: 100 * time.Millisecond,
+			IsRestart:     true,
+		},
+	})
+	fake.outChan <- "Connected to World"
+	waitFor(t, "restart", func() bool {
+		return fake.startCount() == 2
+	})
+}
+
+func TestRecycleSleepingZone(t *testing.T) {
+	recycleInterval = 10 * time.Millisecond
+	defer func() {
+		recycleInterval = 30 * time.Second
+	}()
+
+	_, fake := manageFake(t, AppSpec{
+		DisplayName: "recyclezone",
+		ExeName:     "zone",
+		Recycle: config.RecyclePolicy{
+			MemoryLimit: 100,
+		},
+	})
+
+	fake.outChan <- "Zone booted successfully"
+	reporter.SetAppMetrics(map[string]reporter.Metrics{"recyclezone": {RSS: 200}})
+	time.Sleep(50 * time.Millisecond)
+	if fake.startCount() != 1 {
+		t.Fatalf("expected no recycle while awake, got %d starts", fake.startCount())
+	}
+
+	fake.outChan <- "Entering sleep mode"
+	waitFor(t, "recycle", func() bool {
+		return fake.startCount() == 2
+	})
+}
+
+func TestRestartAllKeepsStoppedApps(t *testing.T) {
+	_, world := manageFake(t, AppSpec{DisplayName: "restartworld", ExeName: "world"})
+	world.outChan <- "Starting EQ Network server on port 9000"
+	waitState(t, "restartworld", reporter.AppStateRunning)
+
+	h, ucs := manageFake(t, AppSpec{DisplayName: "restartucs", ExeName: "ucs"})
+	ucs.outChan <- "Connected to World"
+	waitState(t, "restartucs", reporter.AppStateRunning)
+	err := h.Stop()
+	if err != nil {
+		t.Fatalf("stop: %s", err)
+	}
+	waitState(t, "restartucs", reporter.AppStateStopped)
+
+	err = RestartAllWith(context.Background(), time.Second, RestartOptions{IsSkipPreflight: true})
+	if err != nil {
+		t.Fatalf("restart all: %s", err)
+	}
+	waitFor(t, "world restart", func() bool {
+		return world.startCount() == 2
+	})
+	time.Sleep(50 * time.Millisecond)
+	if ucs.startCount() != 1 {
+		t.Fatalf("expected stopped ucs to stay stopped, got %d starts", ucs.startCount())
+	}
+}
+
+func TestRestartAllAbortsOnStuckApp(t *testing.T) {
+	_, world := manageFake(t, AppSpec{DisplayName: "stuckworld", ExeName: "world"})
+	world.outChan <- "Starting EQ Network server on port 9000"
+	waitState(t, "stuckworld", reporter.AppStateRunning)
+	world.mu.Lock()
+	world.isStuck = true
+	world.mu.Unlock()
+
+	dir := t.TempDir()
+	err := RestartAllWith(context.Background(), 200*time.Millisecond, RestartOptions{
+		Steps:           []AppSpec{{DisplayName: "update", WdPath: dir, ExePath: dir, ExeName: "missing"}},
+		IsSkipPreflight: true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "stuckworld still running") {
+		t.Fatalf("expected stuck app error, got %v", err)
+	}
+	for _, alert := range reporter.Alerts() {
+		if strings.Contains(alert, "update failed") {
+			t.Fatalf("expected no steps to run, got alert %s", alert)
+		}
+	}
+
+	// once it finally exits, it comes back instead of staying stopped
+	world.mu.Lock()
+	world.isStuck = false
+	world.mu.Unlock()
+	world.exit(nil)
+	waitFor(t, "world restart", func() bool {
+		return world.startCount() == 2
+	})
+	reporter.SetAlert("restart all", "")
+}
+
+func TestStaticZoneDependency(t *testing.T) {
+	_, fake := manageFake(t, AppSpec{DisplayName: "qeynos2", ExeName: "zone", Zone: "qeynos2"})
+	waitState(t, "qeynos2", reporter.AppStateStarting)
+	pending := pendingDependencies([]string{"zone"})
+	if len(pending) != 1 {
+		t.Fatalf("expected zone to be pending while qeynos2 starts, got %v", pending)
+	}
+
+	fake.outChan <- "Entering sleep mode"
+	waitState(t, "qeynos2", reporter.AppStateSleeping)
+	pending = pendingDependencies([]string{"zone", "qeynos2"})
+	if len(pending) != 0 {
+		t.Fatalf("expected qeynos2 to satisfy zone, got %v", pending)
+	}
+}
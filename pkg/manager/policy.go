@@ -0,0 +1,94 @@
+package manager
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/xackery/overseer/pkg/config"
+	"github.com/xackery/overseer/pkg/flog"
+	"github.com/xackery/overseer/pkg/reporter"
+)
+
+// onExit applies the restart policy after an app exits on its own. It returns the delay
+// before respawning, or false if the app should stay down until started by request
+func (mgr *manager) onExit(exitErr error, uptime time.Duration) (time.Duration, bool) {
+	policy := mgr.policy
+	switch policy.Mode {
+	case config.RestartNever:
+		flog.Printf("[mgr][%s] restart policy is never, not respawning\n", mgr.displayName)
+		mgr.isStopped = true
+		mgr.setState(reporter.AppStateStopped)
+		return 0, false
+	case config.RestartOnFailure:
+		if exitErr == nil {
+			flog.Printf("[mgr][%s] exited cleanly and restart policy is on-failure, not respawning\n", mgr.displayName)
+			mgr.isStopped = true
+			mgr.setState(reporter.AppStateStopped)
+			return 0, false
+		}
+	}
+
+	now := time.Now()
+	if uptime > policy.ResetAfter {
+		mgr.failures = 0
+	}
+
+	restarts := []time.Time{}
+	for _, restartAt := range mgr.restarts {
+		if now.Sub(restartAt) > policy.Window {
+			continue
+		}
+		restarts = append(restarts, restartAt)
+	}
+	mgr.restarts = append(restarts, now)
+
+	if policy.MaxRestarts > 0 && len(mgr.restarts) > policy.MaxRestarts {
+		alert := fmt.Sprintf("crash loop, %d restarts within %s", len(mgr.restarts)-1, policy.Window)
+		flog.Printf("[mgr][%s] %s, not respawning until started by request\n", mgr.displayName, alert)
+		mgr.isStopped = true
+		mgr.setState(reporter.AppStateCrashLoop)
+		reporter.SetAppAlert(mgr.displayName, alert)
+		return 0, false
+	}
+
+	delay := backoffDelay(policy, mgr.failures)
+	mgr.failures++
+	return delay, true
+}
+
+// clearCrashLoop forgets restart history, used when an app is started by request
+func (mgr *manager) clearCrashLoop() {
+	mgr.restarts = nil
+	mgr.failures = 0
+	reporter.SetAppAlert(mgr.displayName, "")
+}
+
+// backoffMaxDoublings limits how often an uncapped backoff doubles, 10s grows to about a week
+const backoffMaxDoublings = 16
+
+// backoffDelay doubles the policy backoff per consecutive failure, capped at BackoffMax, with jitter.
+// A BackoffMax of 0 does not cap it
+func backoffDelay(policy config.RestartPolicy, failures int) time.Duration {
+	delay := policy.Backoff
+	for i := 0; i < failures && i < backoffMaxDoublings; i++ {
+		if policy.BackoffMax > 0 && delay >= policy.BackoffMax {
+			break
+		}
+		if delay > math.MaxInt64/2 {
+			break
+		}
+		delay *= 2
+	}
+	if policy.BackoffMax > 0 && delay > policy.BackoffMax {
+		delay = policy.BackoffMax
+	}
+	if policy.Jitter > 0 {
+		delay += time.Duration(float64(delay) * policy.Jitter * (2*rand.Float64() - 1))
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return delay
+}
@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/xackery/overseer/pkg/config"
+	"github.com/xackery/overseer/pkg/reporter"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	policy := config.DefaultRestartPolicy()
+	policy.Jitter = 0
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 10 * time.Second},
+		{1, 20 * time.Second},
+		{2, 40 * time.Second},
+		{3, 60 * time.Second},
+		{50, 60 * time.Second},
+	}
+	for _, tt := range tests {
+		got := backoffDelay(policy, tt.failures)
+		if got != tt.want {
+			t.Fatalf("failures %d: expected %s, got %s", tt.failures, tt.want, got)
+		}
+	}
+
+	// without a cap the delay keeps doubling, up to backoffMaxDoublings
+	policy.BackoffMax = 0
+	if backoffDelay(policy, 4) != 160*time.Second {
+		t.Fatalf("expected uncapped backoff to double, got %s", backoffDelay(policy, 4))
+	}
+	if backoffDelay(policy, 1000) != 10*time.Second<<backoffMaxDoublings {
+		t.Fatalf("expected uncapped backoff to stop doubling, got %s", backoffDelay(policy, 1000))
+	}
+	policy.Backoff = time.Duration(math.MaxInt64 / 3)
+	if backoffDelay(policy, 2) <= 0 {
+		t.Fatalf("expected backoff to not overflow, got %s", backoffDelay(policy, 2))
+	}
+	policy.Backoff = 10 * time.Second
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		got := backoffDelay(policy, 0)
+		if got < 5*time.Second || got > 15*time.Second {
+			t.Fatalf("jitter out of range: %s", got)
+		}
+	}
+}
+
+func TestOnExitCrashLoop(t *testing.T) {
+	policy := config.DefaultRestartPolicy()
+	policy.MaxRestarts = 3
+	mgr := &manager{
+		ctx:         context.Background(),
+		displayName: "policytest",
+		policy:      policy,
+	}
+
+	exitErr := fmt.Errorf("wait: exit status 1")
+	for i := 0; i < 3; i++ {
+		_, ok := mgr.onExit(exitErr, time.Second)
+		if !ok {
+			t.Fatalf("restart %d: expected respawn", i)
+		}
+	}
+	_, ok := mgr.onExit(exitErr, time.Second)
+	if ok {
+		t.Fatalf("expected crash loop")
+	}
+	if mgr.state != reporter.AppStateCrashLoop {
+		t.Fatalf("expected crash loop state, got %s", reporter.AppStateString(mgr.state))
+	}
+	report, _ := reporter.Report("policytest")
+	if report.Alert == "" {
+		t.Fatalf("expected alert")
+	}
+}
+
+func TestOnExitOnFailure(t *testing.T) {
+	policy := config.DefaultRestartPolicy()
+	policy.Mode = config.RestartOnFailure
+	mgr := &manager{
+		ctx:         context.Background(),
+		displayName: "policytest2",
+		policy:      policy,
+	}
+
+	_, ok := mgr.onExit(fmt.Errorf("wait: exit status 1"), time.Second)
+	if !ok {
+		t.Fatalf("expected respawn on failure")
+	}
+	_, ok = mgr.onExit(nil, time.Second)
+	if ok {
+		t.Fatalf("expected no respawn on clean exit")
+	}
+	if !mgr.isStopped {
+		t.Fatalf("expected stopped")
+	}
+}
@@ -0,0 +1,161 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/xackery/overseer/pkg/flog"
+	"github.com/xackery/overseer/pkg/reporter"
+	"github.com/xackery/overseer/pkg/signal"
+)
+
+var (
+	// poolInterval is how often the zone pool is resized
+	poolInterval = 10 * time.Second
+	// isShuttingDown pauses the zone pool once Shutdown starts
+	isShuttingDown atomic.Bool
+)
+
+// ZonePool keeps a number of idle zones available, spawning zone processes as players fill
+// them and retiring idle ones once there are more than needed
+type ZonePool struct {
+	Setup SetupType
+	// Min is the fewest zone processes kept, even if all are idle
+	Min int
+	// Max is the most zone processes spawned, even if none are idle
+	Max int
+	// Idle is how many sleeping zones are kept available
+	Idle int
+	// Spec returns the spec to manage a new zone process as
+	Spec func(displayName string) AppSpec
+}
+
+// StartZonePool spawns the minimum zones of a pool, then resizes it until signal is cancelled
+func StartZonePool(pool ZonePool) error {
+	if pool.Max < 1 {
+		return fmt.Errorf("max zones must be at least 1")
+	}
+	if pool.Min > pool.Max {
+		return fmt.Errorf("min zones %d is more than max zones %d", pool.Min, pool.Max)
+	}
+	if pool.Spec == nil {
+		return fmt.Errorf("spec is nil")
+	}
+
+	pool.resize()
+	signal.AddWorker()
+	go func() {
+		defer signal.FinishWorker()
+		for {
+			select {
+			case <-signal.Ctx().Done():
+				return
+			case <-time.After(poolInterval):
+			}
+			pool.resize()
+		}
+	}()
+	return nil
+}
+
+// resize spawns a zone when too few are idle, or retires one when too many are
+func (pool ZonePool) resize() {
+	if isShuttingDown.Load() {
+		return
+	}
+	restartAllMu.Lock()
+	isBusy := isRestartingAll
+	restartAllMu.Unlock()
+	if isBusy {
+		return
+	}
+
+	zones := poolZones()
+	idle := []string{}
+	available := 0
+	for _, name := range zones {
+		state, _ := reporter.State(name)
+		switch state {
+		case reporter.AppStateSleeping:
+			idle = append(idle, name)
+			available++
+		case reporter.AppStateUnknown, reporter.AppStateStarting, reporter.AppStateRestarting:
+			// still booting, and will be idle soon
+			available++
+		}
+	}
+
+	for len(zones) < pool.Max && (len(zones) < pool.Min || available < pool.Idle) {
+		name := nextZoneName(zones)
+		_, err := Manage(pool.Setup, pool.Spec(name))
+		if err != nil {
+			flog.Printf("[mgr][pool] manage %s: %s\n", name, err)
+			return
+		}
+		flog.Printf("[mgr][pool] spawned %s, %d of %d idle, %d zones\n", name, available, pool.Idle, len(zones)+1)
+		zones = append(zones, name)
+		available++
+	}
+
+	if len(idle) == 0 || available <= pool.Idle || len(zones) <= pool.Min {
+		return
+	}
+	// retire the newest idle zone, so zone numbers stay low
+	name := idle[len(idle)-1]
+	h, err := Lookup(name)
+	if err != nil {
+		return
+	}
+	flog.Printf("[mgr][pool] retiring %s, %d of %d idle, %d zones\n", name, available, pool.Idle, len(zones))
+	err = h.Retire()
+	if err != nil {
+		flog.Printf("[mgr][pool] retire %s: %s\n", name, err)
+	}
+}
+
+// poolZones returns the managed dynamic zones, named zone followed by a number, lowest first
+func poolZones() []string {
+	zones := []string{}
+	for _, name := range Names() {
+		_, ok := zoneNumber(name)
+		if ok {
+			zones = append(zones, name)
+		}
+	}
+	sort.Slice(zones, func(i, j int) bool {
+		a, _ := zoneNumber(zones[i])
+		b, _ := zoneNumber(zones[j])
+		return a < b
+	})
+	return zones
+}
+
+// zoneNumber returns the number of a dynamic zone name such as zone3
+func zoneNumber(name string) (int, bool) {
+	if !strings.HasPrefix(name, "zone") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(name, "zone"))
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// nextZoneName returns the lowest zone name not in use
+func nextZoneName(zones []string) string {
+	used := make(map[int]bool)
+	for _, name := range zones {
+		n, _ := zoneNumber(name)
+		used[n] = true
+	}
+	n := 0
+	for used[n] {
+		n++
+	}
+	return fmt.Sprintf("zone%d", n)
+}
@@ -0,0 +1,118 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/xackery/overseer/pkg/reporter"
+	"github.com/xackery/overseer/pkg/runner"
+)
+
+func TestZonePoolResize(t *testing.T) {
+	drainOnce.Do(func() {
+		go func() {
+			for range reporter.SendUpdateChan {
+			}
+		}()
+	})
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "zone"), []byte{}, 0755)
+	if err != nil {
+		t.Fatalf("write exe: %s", err)
+	}
+
+	fakesMu := sync.Mutex{}
+	fakes := make(map[string]*fakeRunner)
+	newRunner = func(mgr *manager) runner.Runner {
+		fake := &fakeRunner{outChan: mgr.outChan}
+		fakesMu.Lock()
+		fakes[mgr.displayName] = fake
+		fakesMu.Unlock()
+		return fake
+	}
+	fake := func(name string) *fakeRunner {
+		t.Helper()
+		var f *fakeRunner
+		waitFor(t, name+" runner", func() bool {
+			fakesMu.Lock()
+			defer fakesMu.Unlock()
+			f = fakes[name]
+			return f != nil && f.startCount() > 0
+		})
+		return f
+	}
+	t.Cleanup(func() {
+		newRunner = defaultRunner
+		for _, name := range poolZones() {
+			h, err := Lookup(name)
+			if err == nil {
+				h.Retire()
+			}
+		}
+		waitFor(t, "pool to retire", func() bool {
+			return len(poolZones()) == 0
+		})
+	})
+
+	pool := ZonePool{
+		Min:  1,
+		Max:  3,
+		Idle: 1,
+		Spec: func(displayName string) AppSpec {
+			return AppSpec{DisplayName: displayName, WdPath: dir, ExePath: dir, ExeName: "zone"}
+		},
+	}
+
+	pool.resize()
+	if len(poolZones()) != 1 {
+		t.Fatalf("expected min of 1 zone, got %v", poolZones())
+	}
+	fake("zone0").outChan <- "Entering sleep mode"
+	waitState(t, "zone0", reporter.AppStateSleeping)
+	pool.resize()
+	if len(poolZones()) != 1 {
+		t.Fatalf("expected no spawn with an idle zone, got %v", poolZones())
+	}
+
+	fake("zone0").outChan <- "Zone booted successfully"
+	waitState(t, "zone0", reporter.AppStateRunning)
+	pool.resize()
+	if len(poolZones()) != 2 {
+		t.Fatalf("expected a spawn once zone0 is busy, got %v", poolZones())
+	}
+
+	fake("zone1").outChan <- "Entering sleep mode"
+	waitState(t, "zone1", reporter.AppStateSleeping)
+	fake("zone0").outChan <- "Entering sleep mode"
+	waitState(t, "zone0", reporter.AppStateSleeping)
+	pool.resize()
+	waitFor(t, "zone1 to retire", func() bool {
+		_, err := Lookup("zone1")
+		return err != nil
+	})
+	_, ok := reporter.State("zone1")
+	if ok {
+		t.Fatalf("expected zone1 to be removed from reporter")
+	}
+
+	// a player arriving between the pool's check and the retire keeps the zone
+	fake("zone0").outChan <- "Zone booted successfully"
+	waitState(t, "zone0", reporter.AppStateRunning)
+	h, err := Lookup("zone0")
+	if err != nil {
+		t.Fatalf("lookup: %s", err)
+	}
+	err = h.Retire()
+	if err != nil {
+		t.Fatalf("retire: %s", err)
+	}
+	fake("zone0").outChan <- "Entering sleep mode"
+	waitState(t, "zone0", reporter.AppStateSleeping)
+	_, err = Lookup("zone0")
+	if err != nil {
+		t.Fatalf("expected zone0 not to retire while it had a player")
+	}
+}
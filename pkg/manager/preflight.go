@@ -0,0 +1,144 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xackery/overseer/pkg/flog"
+	"github.com/xackery/overseer/pkg/reporter"
+	"github.com/xackery/overseer/pkg/runner"
+)
+
+var (
+	preflights      []AppSpec
+	restartAllMu    sync.Mutex
+	isRestartingAll bool
+)
+
+// AddPreflight registers an app, such as shared_memory, that runs to completion before
+// anything starts, and again on RestartAll
+func AddPreflight(spec AppSpec) {
+	mu.Lock()
+	defer mu.Unlock()
+	preflights = append(preflights, spec)
+}
+
+// RunPreflight runs every preflight app in order, stopping at the first failure
+func RunPreflight(ctx context.Context) error {
+	mu.RLock()
+	specs := preflights
+	mu.RUnlock()
+
+	for _, spec := range specs {
+		start := time.Now()
+		lines, err := runner.RunOnce(ctx, spec.DisplayName, spec.WdPath, spec.ExePath, spec.Env, spec.ExeName, spec.Args...)
+		if err != nil {
+			if len(lines) > 0 {
+				return fmt.Errorf("%s: %w, last output: %s", spec.DisplayName, err, lines[len(lines)-1])
+			}
+			return fmt.Errorf("%s: %w", spec.DisplayName, err)
+		}
+		flog.Printf("[mgr][%s] preflight finished in %s\n", spec.DisplayName, time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
+
+// RestartOptions changes what RestartAllWith runs while everything is stopped
+type RestartOptions struct {
+	// Steps run to completion before preflight apps, such as an update
+	Steps []AppSpec
+	// IsSkipPreflight skips preflight apps, such as shared_memory
+	IsSkipPreflight bool
+}
+
+// RestartAll stops every app in reverse order, runs preflight apps, then starts everything again
+func RestartAll(ctx context.Context, timeout time.Duration) error {
+	return RestartAllWith(ctx, timeout, RestartOptions{})
+}
+
+// RestartAllWith is RestartAll, running opts' steps while everything is stopped. A failed
+// step is alerted, but apps still start again. Only apps that were up are started again, so
+// stopped, held and crash looping apps stay down, a held app that was still running included. If an app won't stop before timeout,
+// nothing runs and the apps that did stop are started again
+func RestartAllWith(ctx context.Context, timeout time.Duration, opts RestartOptions) error {
+	restartAllMu.Lock()
+	if isRestartingAll {
+		restartAllMu.Unlock()
+		return fmt.Errorf("restart all already in progress")
+	}
+	isRestartingAll = true
+	restartAllMu.Unlock()
+	defer func() {
+		restartAllMu.Lock()
+		isRestartingAll = false
+		restartAllMu.Unlock()
+	}()
+
+	flog.Printf("[mgr] restarting all\n")
+	mu.RLock()
+	mgrs := []*manager{}
+	for _, name := range order {
+		state, ok := reporter.State(name)
+		report, _ := reporter.Report(name)
+		if !ok || report.IsHeld || isDown(state) {
+			continue
+		}
+		mgrs = append(mgrs, apps[name])
+	}
+	mu.RUnlock()
+
+	running := stopAll(time.Now().Add(timeout))
+	if len(running) > 0 {
+		err := fmt.Errorf("%s still running after %s", strings.Join(running, ", "), timeout)
+		flog.Printf("[mgr] restart all: %s\n", err)
+		reporter.SetAlert("restart all", fmt.Sprintf("aborted, %s", err))
+		startAll(mgrs)
+		return err
+	}
+
+	isStepFailed := false
+	for _, spec := range opts.Steps {
+		start := time.Now()
+		lines, err := runner.RunOnce(ctx, spec.DisplayName, spec.WdPath, spec.ExePath, spec.Env, spec.ExeName, spec.Args...)
+		if err != nil {
+			if len(lines) > 0 {
+				err = fmt.Errorf("%w, last output: %s", err, lines[len(lines)-1])
+			}
+			flog.Printf("[mgr][%s] restart all step: %s\n", spec.DisplayName, err)
+			reporter.SetAlert("restart all", fmt.Sprintf("%s failed: %s", spec.DisplayName, err))
+			isStepFailed = true
+			continue
+		}
+		flog.Printf("[mgr][%s] restart all step finished in %s\n", spec.DisplayName, time.Since(start).Round(time.Millisecond))
+	}
+
+	var err error
+	if !opts.IsSkipPreflight {
+		err = RunPreflight(ctx)
+	}
+	if err != nil {
+		flog.Printf("[mgr] restart all preflight: %s\n", err)
+		reporter.SetAlert("restart all", fmt.Sprintf("failed, apps left stopped: %s", err))
+		return fmt.Errorf("preflight: %w", err)
+	}
+	if !isStepFailed {
+		reporter.SetAlert("restart all", "")
+	}
+
+	startAll(mgrs)
+	flog.Printf("[mgr] restart all complete\n")
+	return nil
+}
+
+// startAll sends a start to each app, in order
+func startAll(mgrs []*manager) {
+	for _, mgr := range mgrs {
+		err := mgr.send(commandStart)
+		if err != nil {
+			flog.Printf("[mgr][%s] restart all: %s\n", mgr.displayName, err)
+		}
+	}
+}
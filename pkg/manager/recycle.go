@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xackery/overseer/pkg/flog"
+	"github.com/xackery/overseer/pkg/reporter"
+	"github.com/xackery/overseer/pkg/runner"
+)
+
+var (
+	// recycleInterval is how often a running zone is checked against its recycle policy
+	recycleInterval = 30 * time.Second
+)
+
+// recycler checks a running app against its recycle policy until ctx is done, sending the reason
+// on recycleChan once a sleeping app should be restarted
+func (mgr *manager) recycler(ctx context.Context, startedAt time.Time) {
+	policy := mgr.recyclePolicy
+	if policy.MemoryLimit == 0 && policy.Interval == 0 {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(mgr.recycleInterval):
+		}
+
+		report, ok := reporter.Report(mgr.displayName)
+		if !ok || report.State != reporter.AppStateString(reporter.AppStateSleeping) {
+			continue
+		}
+
+		reason := ""
+		if policy.Interval > 0 && time.Since(startedAt) >= policy.Interval {
+			reason = fmt.Sprintf("up for %s", time.Since(startedAt).Round(time.Minute))
+		}
+		if policy.MemoryLimit > 0 && report.Metrics != nil && report.Metrics.RSS >= policy.MemoryLimit {
+			reason = fmt.Sprintf("using %s, limit %s", reporter.FormatBytes(report.Metrics.RSS), reporter.FormatBytes(policy.MemoryLimit))
+		}
+		if reason == "" {
+			continue
+		}
+		select {
+		case mgr.recycleChan <- reason:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// onRecycle restarts an app for its recycle policy, as long as it is still sleeping
+func (mgr *manager) onRecycle(reason string, run runner.Runner) {
+	if mgr.isRestarting || mgr.isStopped {
+		return
+	}
+	if mgr.state != reporter.AppStateSleeping {
+		flog.Printf("[mgr][%s] skipping recycle (%s), no longer sleeping\n", mgr.displayName, reason)
+		return
+	}
+	flog.Printf("[mgr][%s] recycling while sleeping: %s\n", mgr.displayName, reason)
+	mgr.isRestarting = true
+	go mgr.stop(run)
+}
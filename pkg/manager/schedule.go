@@ -0,0 +1,136 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xackery/overseer/pkg/cron"
+	"github.com/xackery/overseer/pkg/flog"
+	"github.com/xackery/overseer/pkg/reporter"
+	"github.com/xackery/overseer/pkg/signal"
+	"github.com/xackery/overseer/pkg/telnet"
+)
+
+var (
+	// restartAll restarts every app for a schedule, replaced in tests
+	restartAll = RestartAllWith
+)
+
+// RestartSchedule restarts every running app when Cron matches, broadcasting countdown warnings
+// to players over world's telnet console first, and locking world so no one logs in mid restart.
+// Apps stopped or held by an operator stay down
+type RestartSchedule struct {
+	Cron *cron.Schedule
+	// Warnings are how long before the restart players are warned, longest first
+	Warnings []time.Duration
+	Options  RestartOptions
+}
+
+// StartRestartSchedule waits for each scheduled restart until signal is cancelled
+func StartRestartSchedule(schedule RestartSchedule) error {
+	if schedule.Cron == nil {
+		return fmt.Errorf("cron is nil")
+	}
+
+	signal.AddWorker()
+	go func() {
+		defer signal.FinishWorker()
+		ctx := signal.Ctx()
+		for {
+			at := schedule.Cron.Next(time.Now())
+			reporter.SetNextRestart(at)
+			if at.IsZero() {
+				flog.Printf("[mgr] restart schedule %s never matches\n", schedule.Cron)
+				return
+			}
+			flog.Printf("[mgr] next scheduled restart at %s\n", at.Format(time.RFC1123))
+			err := schedule.run(ctx, at)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				flog.Printf("[mgr] scheduled restart: %s\n", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// run warns players as at approaches, then restarts everything at at. Warnings already
+// past, such as when overseer starts shortly before a restart, are skipped
+func (schedule RestartSchedule) run(ctx context.Context, at time.Time) error {
+	for _, warning := range schedule.Warnings {
+		warnAt := at.Add(-warning)
+		if time.Now().After(warnAt) {
+			continue
+		}
+		if !sleepUntil(ctx, warnAt) {
+			return ctx.Err()
+		}
+		broadcast(ctx, fmt.Sprintf("The server is restarting in %s", countdown(warning)))
+	}
+	if !sleepUntil(ctx, at) {
+		return ctx.Err()
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, telnet.DefaultTimeout)
+	err := telnet.WorldClient().Lock(reqCtx)
+	cancel()
+	if err != nil {
+		flog.Printf("[mgr] scheduled restart lock world: %s\n", err)
+	}
+	broadcast(ctx, "The server is restarting now")
+
+	flog.Printf("[mgr] scheduled restart\n")
+	err = restartAll(ctx, ShutdownTimeout, schedule.Options)
+	if err != nil {
+		// world is unlocked by restarting, so only a restart that never happened leaves it locked
+		reqCtx, cancel := context.WithTimeout(ctx, telnet.DefaultTimeout)
+		unlockErr := telnet.WorldClient().Unlock(reqCtx)
+		cancel()
+		if unlockErr != nil {
+			flog.Printf("[mgr] scheduled restart unlock world: %s\n", unlockErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// broadcast sends a message to players, a world that can't be reached is only logged
+func broadcast(ctx context.Context, message string) {
+	flog.Printf("[mgr] broadcast: %s\n", message)
+	reqCtx, cancel := context.WithTimeout(ctx, telnet.DefaultTimeout)
+	defer cancel()
+	err := telnet.WorldClient().Broadcast(reqCtx, message)
+	if err != nil {
+		flog.Printf("[mgr] broadcast: %s\n", err)
+	}
+}
+
+// sleepUntil waits until at, returns false if ctx is done first
+func sleepUntil(ctx context.Context, at time.Time) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(time.Until(at)):
+		return true
+	}
+}
+
+// countdown describes a warning for players, such as 15 minutes or 10 seconds
+func countdown(d time.Duration) string {
+	unit := "second"
+	n := int(d.Round(time.Second) / time.Second)
+	if d >= time.Hour && d%time.Hour == 0 {
+		unit = "hour"
+		n = int(d / time.Hour)
+	} else if d >= time.Minute && d%time.Minute == 0 {
+		unit = "minute"
+		n = int(d / time.Minute)
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
@@ -0,0 +1,127 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xackery/overseer/pkg/config"
+	"github.com/xackery/overseer/pkg/reporter"
+	"github.com/xackery/overseer/pkg/telnet"
+	"github.com/xackery/overseer/pkg/telnet/telnettest"
+)
+
+func TestRestartScheduleRun(t *testing.T) {
+	server, err := telnettest.NewServer()
+	if err != nil {
+		t.Fatalf("new server: %s", err)
+	}
+	defer server.Close()
+	server.SetCommand("lock", "World locked.")
+	server.SetCommand("unlock", "World unlocked.")
+	server.SetCommand("broadcast The server is restarting in 1 second", "")
+	server.SetCommand("broadcast The server is restarting now", "")
+	telnet.SetClient(telnet.NewClient(server.Addr(), "", ""))
+	defer telnet.SetClient(nil)
+
+	restarts := 0
+	restartAll = func(ctx context.Context, timeout time.Duration, opts RestartOptions) error {
+		restarts++
+		if !opts.IsSkipPreflight {
+			return fmt.Errorf("expected preflight to be skipped")
+		}
+		return fmt.Errorf("restart all already in progress")
+	}
+	defer func() { restartAll = RestartAllWith }()
+
+	schedule := RestartSchedule{
+		// the 10 second warning is already past, and skipped
+		Warnings: []time.Duration{10 * time.Second, time.Second},
+		Options:  RestartOptions{IsSkipPreflight: true},
+	}
+	err = schedule.run(context.Background(), time.Now().Add(1500*time.Millisecond))
+	if err == nil {
+		t.Fatalf("expected the restart error")
+	}
+	if restarts != 1 {
+		t.Fatalf("expected 1 restart, got %d", restarts)
+	}
+
+	want := []string{
+		"broadcast The server is restarting in 1 second",
+		"lock",
+		"broadcast The server is restarting now",
+		// a failed restart leaves world running, so it is unlocked again
+		"unlock",
+	}
+	received := server.Received()
+	// the first two commands turn off echo and messages
+	if len(received) != len(want)+2 {
+		t.Fatalf("expected %v, got %v", want, received)
+	}
+	for i, cmd := range want {
+		if received[i+2] != cmd {
+			t.Fatalf("expected %v, got %v", want, received[2:])
+		}
+	}
+}
+
+func TestRestartScheduleStuckApp(t *testing.T) {
+	server, err := telnettest.NewServer()
+	if err != nil {
+		t.Fatalf("new server: %s", err)
+	}
+	defer server.Close()
+	server.SetCommand("lock", "World locked.")
+	server.SetCommand("unlock", "World unlocked.")
+	server.SetCommand("broadcast The server is restarting now", "")
+	telnet.SetClient(telnet.NewClient(server.Addr(), "", ""))
+	defer telnet.SetClient(nil)
+
+	ShutdownTimeout = 200 * time.Millisecond
+	defer func() { ShutdownTimeout = config.DefaultShutdownTimeout }()
+
+	_, world := manageFake(t, AppSpec{DisplayName: "scheduleworld", ExeName: "world"})
+	world.outChan <- "Starting EQ Network server on port 9000"
+	waitState(t, "scheduleworld", reporter.AppStateRunning)
+	world.mu.Lock()
+	world.isStuck = true
+	world.mu.Unlock()
+	defer func() {
+		world.mu.Lock()
+		world.isStuck = false
+		world.mu.Unlock()
+		world.exit(nil)
+		reporter.SetAlert("restart all", "")
+	}()
+
+	// shared_memory would fail here, so reaching it would be a different error
+	AddPreflight(AppSpec{DisplayName: "shared_memory", WdPath: t.TempDir(), ExeName: "missing"})
+	defer func() { preflights = nil }()
+
+	schedule := RestartSchedule{}
+	err = schedule.run(context.Background(), time.Now())
+	if err == nil || !strings.Contains(err.Error(), "scheduleworld still running") {
+		t.Fatalf("expected stuck world error, got %v", err)
+	}
+	received := server.Received()
+	if received[len(received)-1] != "unlock" {
+		t.Fatalf("expected world unlocked after an aborted restart, got %v", received)
+	}
+}
+
+func TestCountdown(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		15 * time.Minute: "15 minutes",
+		time.Minute:      "1 minute",
+		90 * time.Second: "90 seconds",
+		10 * time.Second: "10 seconds",
+		2 * time.Hour:    "2 hours",
+	} {
+		if countdown(d) != want {
+			t.Fatalf("%s: expected %s, got %s", d, want, countdown(d))
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+	"github.com/xackery/overseer/pkg/flog"
+	"github.com/xackery/overseer/pkg/reporter"
+	"github.com/xackery/overseer/pkg/runner"
+	"github.com/xackery/overseer/pkg/telnet"
+)
+
+var (
+	// watchdogInterval is how often a running app is checked for hangs
+	watchdogInterval = 5 * time.Second
+	// telnetCheckInterval is how often world's telnet console is checked
+	telnetCheckInterval = 30 * time.Second
+)
+
+const (
+	// watchdogCPUPercent is the cpu usage treated as a pegged core
+	watchdogCPUPercent = 95.0
+)
+
+// watchdog checks a running app for hangs until ctx is done, sending the reason on hangChan,
+// or an empty reason once a hung app recovers
+func (mgr *manager) watchdog(ctx context.Context, pid int) {
+	policy := mgr.watchdogPolicy
+	if policy.OutputTimeout == 0 && policy.CPUTimeout == 0 && policy.TelnetTimeout == 0 {
+		return
+	}
+
+	var proc *process.Process
+	if pid != 0 && policy.CPUTimeout > 0 {
+		var err error
+		proc, err = process.NewProcessWithContext(ctx, int32(pid))
+		if err != nil {
+			flog.Printf("[mgr][%s] watchdog: %s\n", mgr.displayName, err)
+		}
+	}
+	// world's console is only checked if eqemu_config.json says where it is
+	isTelnetChecked := strings.TrimSuffix(mgr.exeName, ".exe") == "world" && policy.TelnetTimeout > 0 && telnet.IsEnabled()
+	cpuHighSince := time.Time{}
+	telnetOKAt := time.Now()
+	telnetCheckAt := time.Time{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(mgr.watchdogInterval):
+		}
+
+		state, _ := reporter.State(mgr.displayName)
+		switch state {
+		case reporter.AppStateRunning, reporter.AppStateSleeping, reporter.AppStateErroring, reporter.AppStateHung:
+		default:
+			// still starting, or on its way down
+			continue
+		}
+
+		reason := ""
+		if policy.OutputTimeout > 0 && state != reporter.AppStateSleeping {
+			quiet := time.Since(time.Unix(0, mgr.lastOutputAt.Load()))
+			if quiet > policy.OutputTimeout {
+				reason = fmt.Sprintf("no output for %s", quiet.Round(time.Second))
+			}
+		}
+
+		if proc != nil {
+			percent, err := proc.PercentWithContext(ctx, 0)
+			if err == nil && percent >= watchdogCPUPercent {
+				if cpuHighSince.IsZero() {
+					cpuHighSince = time.Now()
+				}
+				if time.Since(cpuHighSince) > policy.CPUTimeout {
+					reason = fmt.Sprintf("%.0f%% cpu for %s", percent, time.Since(cpuHighSince).Round(time.Second))
+				}
+			} else {
+				cpuHighSince = time.Time{}
+			}
+		}
+
+		if isTelnetChecked && time.Since(telnetCheckAt) >= telnetCheckInterval {
+			telnetCheckAt = time.Now()
+			err := telnet.Ping(ctx)
+			if err == nil {
+				telnetOKAt = time.Now()
+			} else {
+				flog.Printf("[mgr][%s] watchdog telnet: %s\n", mgr.displayName, err)
+			}
+		}
+		if isTelnetChecked && time.Since(telnetOKAt) > policy.TelnetTimeout {
+			reason = fmt.Sprintf("telnet unresponsive for %s", time.Since(telnetOKAt).Round(time.Second))
+		}
+
+		if reason != "" && state == reporter.AppStateHung {
+			continue
+		}
+		if reason == "" && state != reporter.AppStateHung {
+			continue
+		}
+		select {
+		case mgr.hangChan <- reason:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// onHang handles a hang reported by the watchdog, an empty reason means the app recovered
+func (mgr *manager) onHang(reason string, run runner.Runner) {
+	if reason == "" {
+		if mgr.state != reporter.AppStateHung {
+			return
+		}
+		flog.Printf("[mgr][%s] recovered from hang\n", mgr.displayName)
+		mgr.isHung = false
+		mgr.setState(reporter.AppStateRunning)
+		reporter.SetAppAlert(mgr.displayName, "")
+		return
+	}
+
+	flog.Printf("[mgr][%s] hung: %s\n", mgr.displayName, reason)
+	mgr.isHung = true
+	mgr.setState(reporter.AppStateHung)
+	reporter.SetAppAlert(mgr.displayName, "hung, "+reason)
+	if !mgr.watchdogPolicy.IsRestart {
+		return
+	}
+	flog.Printf("[mgr][%s] restarting hung app\n", mgr.displayName)
+	go mgr.stop(run)
+}
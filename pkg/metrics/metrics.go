@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+	"github.com/xackery/overseer/pkg/flog"
+	"github.com/xackery/overseer/pkg/reporter"
+	"github.com/xackery/overseer/pkg/signal"
+)
+
+var (
+	// Interval is how often every managed pid is sampled
+	Interval = 5 * time.Second
+)
+
+// New starts sampling the cpu, memory, thread and fd usage of every app with a pid, until signal is cancelled
+func New() {
+	signal.AddWorker()
+	go func() {
+		defer signal.FinishWorker()
+		poll(signal.Ctx())
+	}()
+}
+
+func poll(ctx context.Context) {
+	// processes are kept between samples, cpu percent is measured since the previous call
+	procs := make(map[string]*process.Process)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(Interval):
+		}
+		reporter.SetAppMetrics(sample(ctx, procs))
+	}
+}
+
+// sample measures each app with a pid, forgetting processes that are gone
+func sample(ctx context.Context, procs map[string]*process.Process) map[string]reporter.Metrics {
+	samples := make(map[string]reporter.Metrics)
+	seen := make(map[string]bool)
+	for _, report := range reporter.Reports() {
+		if report.PID == 0 {
+			continue
+		}
+		seen[report.Name] = true
+		proc, ok := procs[report.Name]
+		if !ok || proc.Pid != int32(report.PID) {
+			var err error
+			proc, err = process.NewProcessWithContext(ctx, int32(report.PID))
+			if err != nil {
+				delete(procs, report.Name)
+				continue
+			}
+			procs[report.Name] = proc
+			// the first cpu percent call only sets a baseline
+			_, _ = proc.PercentWithContext(ctx, 0)
+			continue
+		}
+
+		m := reporter.Metrics{At: time.Now()}
+		var err error
+		m.CPUPercent, err = proc.PercentWithContext(ctx, 0)
+		if err != nil {
+			flog.Printf("[metrics][%s] cpu: %s\n", report.Name, err)
+			delete(procs, report.Name)
+			continue
+		}
+		mem, err := proc.MemoryInfoWithContext(ctx)
+		if err == nil {
+			m.RSS = mem.RSS
+		}
+		m.Threads, _ = proc.NumThreadsWithContext(ctx)
+		// not implemented on windows
+		m.FDs, _ = proc.NumFDsWithContext(ctx)
+		samples[report.Name] = m
+	}
+	for name := range procs {
+		if !seen[name] {
+			delete(procs, name)
+		}
+	}
+	return samples
+}
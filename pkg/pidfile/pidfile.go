@@ -0,0 +1,190 @@
+package pidfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+const (
+	// DefaultPath is where overseer keeps the pids it owns
+	DefaultPath = "overseer.pids.json"
+	// OverseerName is the entry overseer records for itself
+	OverseerName = "overseer"
+)
+
+var (
+	mu      sync.Mutex
+	path    string
+	entries = make(map[string]Entry)
+	// keptEntries are leftovers from a previous run that were left alone, recorded so they
+	// are found again next run. Their names may clash with entries
+	keptEntries []Entry
+)
+
+// Entry is a process overseer started
+type Entry struct {
+	Name string `json:"name"`
+	PID  int    `json:"pid"`
+	// CreateTime is when the process was created in unix milliseconds, so a reused pid isn't mistaken for it
+	CreateTime int64 `json:"create_time"`
+}
+
+// New starts a fresh state file at p, recording the current process as overseer. kept are
+// leftovers of a previous run that are left running, carried over so they aren't forgotten
+func New(p string, kept ...Entry) error {
+	mu.Lock()
+	path = p
+	entries = make(map[string]Entry)
+	keptEntries = kept
+	mu.Unlock()
+	return Set(OverseerName, os.Getpid())
+}
+
+// Set records that name is running as pid
+func Set(name string, pid int) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if path == "" {
+		return nil
+	}
+	entry := Entry{Name: name, PID: pid}
+	p, err := process.NewProcess(int32(pid))
+	if err == nil {
+		entry.CreateTime, _ = p.CreateTime()
+	}
+	entries[name] = entry
+	return save()
+}
+
+// Remove forgets name, once it has exited
+func Remove(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if path == "" {
+		return nil
+	}
+	_, ok := entries[name]
+	if !ok {
+		return nil
+	}
+	delete(entries, name)
+	return save()
+}
+
+// Close removes the state file on a clean exit. If apps are still running, it is kept
+// so the next overseer can find them
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if path == "" {
+		return nil
+	}
+	defer func() {
+		path = ""
+	}()
+	delete(entries, OverseerName)
+	alive := []Entry{}
+	for _, entry := range keptEntries {
+		if IsAlive(entry) {
+			alive = append(alive, entry)
+		}
+	}
+	keptEntries = alive
+	if len(entries) > 0 || len(keptEntries) > 0 {
+		return save()
+	}
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove: %w", err)
+	}
+	return nil
+}
+
+func save() error {
+	list := []Entry{}
+	for _, entry := range entries {
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	list = append(list, keptEntries...)
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Leftovers reads a state file from a previous run, returning each entry that is still running
+func Leftovers(p string) ([]Entry, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read %s: %w", p, err)
+	}
+	list := []Entry{}
+	err = json.Unmarshal(data, &list)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %w", p, err)
+	}
+
+	alive := []Entry{}
+	for _, entry := range list {
+		if entry.PID == os.Getpid() {
+			continue
+		}
+		if !IsAlive(entry) {
+			continue
+		}
+		alive = append(alive, entry)
+	}
+	return alive, nil
+}
+
+// IsAlive returns true if the process of an entry is still running
+func IsAlive(entry Entry) bool {
+	if entry.PID <= 0 {
+		return false
+	}
+	p, err := process.NewProcess(int32(entry.PID))
+	if err != nil {
+		return false
+	}
+	createTime, err := p.CreateTime()
+	if err != nil {
+		return false
+	}
+	if entry.CreateTime != 0 && createTime != entry.CreateTime {
+		return false
+	}
+	status, err := p.Status()
+	if err == nil && len(status) > 0 && status[0] == process.Zombie {
+		return false
+	}
+	return true
+}
+
+// Find returns the entry named name, or of its base name so zone matches zone0
+func Find(list []Entry, name string) (Entry, bool) {
+	name = strings.ToLower(name)
+	for _, entry := range list {
+		entryName := strings.ToLower(entry.Name)
+		if entryName == name || strings.TrimRight(entryName, "0123456789") == name {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
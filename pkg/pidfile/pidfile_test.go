@@ -0,0 +1,87 @@
+package pidfile
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestLeftovers(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping test; requires sleep")
+	}
+
+	cmd := exec.Command("sleep", "30")
+	err := cmd.Start()
+	if err != nil {
+		t.Fatalf("start: %s", err)
+	}
+	defer cmd.Process.Kill()
+
+	path := filepath.Join(t.TempDir(), "pids.json")
+	err = New(path)
+	if err != nil {
+		t.Fatalf("new: %s", err)
+	}
+	err = Set("zone1", cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("set: %s", err)
+	}
+	err = Set("world", 999999)
+	if err != nil {
+		t.Fatalf("set: %s", err)
+	}
+	err = Remove("world")
+	if err != nil {
+		t.Fatalf("remove: %s", err)
+	}
+	err = Set("ucs", 999999)
+	if err != nil {
+		t.Fatalf("set: %s", err)
+	}
+
+	// the current process is skipped, and ucs isn't running
+	entries, err := Leftovers(path)
+	if err != nil {
+		t.Fatalf("leftovers: %s", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "zone1" {
+		t.Fatalf("expected only zone1, got %+v", entries)
+	}
+	_, ok := Find(entries, "zone")
+	if !ok {
+		t.Fatalf("expected zone to match zone1")
+	}
+
+	err = Close()
+	if err != nil {
+		t.Fatalf("close: %s", err)
+	}
+	_, err = os.Stat(path)
+	if err != nil {
+		t.Fatalf("expected state file to be kept while zone1 runs: %s", err)
+	}
+
+	// ignored leftovers are carried into the next run's file, even with a new zone1 in it
+	err = New(path, entries...)
+	if err != nil {
+		t.Fatalf("new: %s", err)
+	}
+	err = Set("zone1", os.Getpid())
+	if err != nil {
+		t.Fatalf("set: %s", err)
+	}
+	entries, err = Leftovers(path)
+	if err != nil {
+		t.Fatalf("leftovers: %s", err)
+	}
+	if len(entries) != 1 || entries[0].PID != cmd.Process.Pid {
+		t.Fatalf("expected the ignored zone1 to be kept, got %+v", entries)
+	}
+	err = Close()
+	if err != nil {
+		t.Fatalf("close: %s", err)
+	}
+}
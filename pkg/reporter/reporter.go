@@ -1,6 +1,8 @@
 package reporter
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -9,13 +11,87 @@ import (
 var (
 	mu             sync.RWMutex
 	apps           = make(map[string]*App)
+	alerts         = make(map[string]string) // alerts not tied to an app, keyed by source
+	worldStats     = WorldStats{PopularClass: "None"}
+	nextRestart    time.Time
 	SendUpdateChan = make(chan bool, 1000)
 )
 
 type App struct {
 	Status AppState
 	PID    int
-	start  time.Time
+	// ID identifies what is running, a pid for processes or a container id for docker
+	ID           string
+	RestartCount int
+	LastError    string
+	IsHeld       bool
+	// Alert is set when an app needs attention, such as being in a crash loop
+	Alert string
+	// LastExit is how the app last exited, nil if it never has
+	LastExit *AppExit
+	// Zone is the short name a static zone process serves, empty for other apps
+	Zone string
+	// Counters are how often each counter rule matched the app's output
+	Counters map[string]int
+	events   []AppEvent
+	// metrics is a ring buffer of resource samples, next is where the next sample goes
+	metrics     [MetricsHistorySize]Metrics
+	metricCount int
+	metricNext  int
+	start       time.Time
+}
+
+// MetricsHistorySize is how many samples are kept per app
+const MetricsHistorySize = 60
+
+// EventHistorySize is how many events are kept per app
+const EventHistorySize = 50
+
+// AppEvent is a named event raised by a line of app output
+type AppEvent struct {
+	Name string    `json:"name"`
+	Line string    `json:"line"`
+	At   time.Time `json:"at"`
+}
+
+// Metrics is a resource usage sample of an app's process
+type Metrics struct {
+	CPUPercent float64 `json:"cpu_percent"`
+	// RSS is resident memory in bytes
+	RSS     uint64 `json:"rss"`
+	Threads int32  `json:"threads"`
+	// FDs is the number of open file descriptors, 0 where unsupported
+	FDs int32     `json:"fds"`
+	At  time.Time `json:"at"`
+}
+
+// AppExit describes how an app exited
+type AppExit struct {
+	// Code is the exit code, or -1 if killed by a signal
+	Code         int    `json:"code"`
+	Signal       string `json:"signal,omitempty"`
+	IsCoreDumped bool   `json:"is_core_dumped"`
+	// IsKilled is true when the app did not exit within its stop grace period and was killed
+	IsKilled bool          `json:"is_killed"`
+	Runtime  time.Duration `json:"runtime"`
+	At       time.Time     `json:"at"`
+}
+
+// AppReport is a snapshot of an app, used by the control api
+type AppReport struct {
+	Name         string         `json:"name"`
+	PID          int            `json:"pid"`
+	ID           string         `json:"id,omitempty"`
+	State        string         `json:"state"`
+	Uptime       string         `json:"uptime"`
+	RestartCount int            `json:"restart_count"`
+	LastError    string         `json:"last_error"`
+	IsHeld       bool           `json:"is_held"`
+	Alert        string         `json:"alert,omitempty"`
+	LastExit     *AppExit       `json:"last_exit,omitempty"`
+	Metrics      *Metrics       `json:"metrics,omitempty"`
+	Zone         string         `json:"zone,omitempty"`
+	Counters     map[string]int `json:"counters,omitempty"`
 }
 
 func (a *App) Uptime() string {
@@ -48,10 +124,15 @@ const (
 	AppStateRestarting
 	AppStateSleeping
 	AppStateErroring
+	AppStateHeld
+	AppStateCrashLoop
+	AppStateHung
 )
 
 type AppStateReport struct {
-	States         map[string]AppState
+	States map[string]AppState
+	// StaticZones are the states of static zone processes, keyed by app name
+	StaticZones    map[string]AppState
 	ZoneTotal      int
 	ZoneUnknown    int
 	ZoneStarting   int
@@ -60,6 +141,9 @@ type AppStateReport struct {
 	ZoneRestarting int
 	ZoneSleeping   int
 	ZoneErroring   int
+	ZoneHeld       int
+	ZoneCrashLoop  int
+	ZoneHung       int
 }
 
 // ZoneUpdate updates the status of a zone.
@@ -108,6 +192,409 @@ func SetAppPID(name string, pid int) {
 	}
 }
 
+// SetAppID sets what identifies a running app, such as a container id
+func SetAppID(name string, id string) {
+	mu.Lock()
+	defer mu.Unlock()
+	app, ok := apps[name]
+	if !ok {
+		app = &App{
+			start: time.Now(),
+		}
+		apps[name] = app
+	}
+	isUpdate := false
+	if app.ID != id {
+		isUpdate = true
+	}
+	app.ID = id
+	if isUpdate {
+		SendUpdateChan <- true
+	}
+}
+
+// SetAppRestartCount sets how many times an app has been restarted
+func SetAppRestartCount(name string, count int) {
+	mu.Lock()
+	defer mu.Unlock()
+	app, ok := apps[name]
+	if !ok {
+		app = &App{
+			start: time.Now(),
+		}
+		apps[name] = app
+	}
+	isUpdate := false
+	if app.RestartCount != count {
+		isUpdate = true
+	}
+	app.RestartCount = count
+	if isUpdate {
+		SendUpdateChan <- true
+	}
+}
+
+// SetAppLastError sets the last error line reported by an app
+func SetAppLastError(name string, line string) {
+	mu.Lock()
+	defer mu.Unlock()
+	app, ok := apps[name]
+	if !ok {
+		app = &App{
+			start: time.Now(),
+		}
+		apps[name] = app
+	}
+	app.LastError = line
+}
+
+// SetAppHeld flags an app as held, meaning it will not be respawned
+func SetAppHeld(name string, isHeld bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	app, ok := apps[name]
+	if !ok {
+		app = &App{
+			start: time.Now(),
+		}
+		apps[name] = app
+	}
+	isUpdate := false
+	if app.IsHeld != isHeld {
+		isUpdate = true
+	}
+	app.IsHeld = isHeld
+	if isUpdate {
+		SendUpdateChan <- true
+	}
+}
+
+// SetAppAlert sets an alert on an app, an empty alert clears it
+func SetAppAlert(name string, alert string) {
+	mu.Lock()
+	defer mu.Unlock()
+	app, ok := apps[name]
+	if !ok {
+		app = &App{
+			start: time.Now(),
+		}
+		apps[name] = app
+	}
+	isUpdate := false
+	if app.Alert != alert {
+		isUpdate = true
+	}
+	app.Alert = alert
+	if isUpdate {
+		SendUpdateChan <- true
+	}
+}
+
+// SetAppZone marks an app as the static zone process for a zone short name
+func SetAppZone(name string, zone string) {
+	mu.Lock()
+	defer mu.Unlock()
+	app, ok := apps[name]
+	if !ok {
+		app = &App{
+			start: time.Now(),
+		}
+		apps[name] = app
+	}
+	isUpdate := false
+	if app.Zone != zone {
+		isUpdate = true
+	}
+	app.Zone = zone
+	if isUpdate {
+		SendUpdateChan <- true
+	}
+}
+
+// AddAppCounter increments a named counter of an app
+func AddAppCounter(name string, counter string) {
+	mu.Lock()
+	defer mu.Unlock()
+	app, ok := apps[name]
+	if !ok {
+		app = &App{
+			start: time.Now(),
+		}
+		apps[name] = app
+	}
+	if app.Counters == nil {
+		app.Counters = make(map[string]int)
+	}
+	app.Counters[counter]++
+}
+
+// AddAppEvent records an event of an app, keeping the latest EventHistorySize
+func AddAppEvent(name string, event AppEvent) {
+	mu.Lock()
+	defer mu.Unlock()
+	app, ok := apps[name]
+	if !ok {
+		app = &App{
+			start: time.Now(),
+		}
+		apps[name] = app
+	}
+	app.events = append(app.events, event)
+	if len(app.events) > EventHistorySize {
+		app.events = app.events[len(app.events)-EventHistorySize:]
+	}
+	SendUpdateChan <- true
+}
+
+// AppEvents returns the recorded events of an app, oldest first
+func AppEvents(name string) []AppEvent {
+	mu.RLock()
+	defer mu.RUnlock()
+	app, ok := apps[name]
+	if !ok {
+		return nil
+	}
+	return append([]AppEvent{}, app.events...)
+}
+
+// SetAppExit records how an app exited
+func SetAppExit(name string, exit AppExit) {
+	mu.Lock()
+	defer mu.Unlock()
+	app, ok := apps[name]
+	if !ok {
+		app = &App{
+			start: time.Now(),
+		}
+		apps[name] = app
+	}
+	app.LastExit = &exit
+	SendUpdateChan <- true
+}
+
+// SetAppMetrics records a batch of samples, keyed by app name, and sends a single update
+func SetAppMetrics(samples map[string]Metrics) {
+	mu.Lock()
+	defer mu.Unlock()
+	for name, sample := range samples {
+		app, ok := apps[name]
+		if !ok {
+			continue
+		}
+		app.metrics[app.metricNext] = sample
+		app.metricNext = (app.metricNext + 1) % MetricsHistorySize
+		if app.metricCount < MetricsHistorySize {
+			app.metricCount++
+		}
+	}
+	if len(samples) > 0 {
+		SendUpdateChan <- true
+	}
+}
+
+// AppMetrics returns the sample history of an app, oldest first
+func AppMetrics(name string) []Metrics {
+	mu.RLock()
+	defer mu.RUnlock()
+	app, ok := apps[name]
+	if !ok {
+		return nil
+	}
+	return app.history()
+}
+
+// LatestMetrics returns the newest sample of an app, if any
+func LatestMetrics(name string) (Metrics, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	app, ok := apps[name]
+	if !ok {
+		return Metrics{}, false
+	}
+	return app.latestMetrics()
+}
+
+func (a *App) latestMetrics() (Metrics, bool) {
+	if a.metricCount == 0 {
+		return Metrics{}, false
+	}
+	return a.metrics[(a.metricNext+MetricsHistorySize-1)%MetricsHistorySize], true
+}
+
+func (a *App) history() []Metrics {
+	result := make([]Metrics, 0, a.metricCount)
+	start := (a.metricNext - a.metricCount + MetricsHistorySize) % MetricsHistorySize
+	for i := 0; i < a.metricCount; i++ {
+		result = append(result, a.metrics[(start+i)%MetricsHistorySize])
+	}
+	return result
+}
+
+// FormatBytes returns a human readable size, such as 12.3 MB
+func FormatBytes(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := uint64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// RemoveApp forgets an app that is no longer managed
+func RemoveApp(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	_, ok := apps[name]
+	if !ok {
+		return
+	}
+	delete(apps, name)
+	SendUpdateChan <- true
+}
+
+// SetAlert sets an alert that is not tied to an app, an empty alert clears it
+func SetAlert(source string, alert string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if alerts[source] == alert {
+		return
+	}
+	if alert == "" {
+		delete(alerts, source)
+	} else {
+		alerts[source] = alert
+	}
+	SendUpdateChan <- true
+}
+
+// Alerts returns every active alert as "name: alert", sorted
+func Alerts() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	result := []string{}
+	for name, app := range apps {
+		if app.Alert == "" {
+			continue
+		}
+		result = append(result, name+": "+app.Alert)
+	}
+	for source, alert := range alerts {
+		result = append(result, source+": "+alert)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// WorldStats are aggregates of the players online, collected from world's telnet console
+type WorldStats struct {
+	Online       int       `json:"online"`
+	AvgLevel     int       `json:"avg_level"`
+	PopularClass string    `json:"popular_class"`
+	Players      []Player  `json:"players"`
+	At           time.Time `json:"at"`
+}
+
+// Player is a character online in world
+type Player struct {
+	Name          string `json:"name"`
+	Level         int    `json:"level"`
+	Class         string `json:"class"`
+	Race          int    `json:"race"`
+	ZoneID        int    `json:"zone_id"`
+	InstanceID    int    `json:"instance_id,omitempty"`
+	Zone          string `json:"zone"`
+	GuildID       int64  `json:"guild_id,omitempty"`
+	IsLFG         bool   `json:"is_lfg"`
+	IsGM          bool   `json:"is_gm"`
+	ClientVersion int    `json:"client_version"`
+	// App is the zone process hosting the player, empty if it is not managed by overseer
+	App string `json:"app,omitempty"`
+}
+
+// ZonePopulation is how many players a zone process is hosting
+type ZonePopulation struct {
+	// App is the zone process, or the zone's short name if it is not managed by overseer
+	App     string `json:"app"`
+	Zones   string `json:"zones"`
+	Players int    `json:"players"`
+}
+
+// SetWorldStats sets the latest world stats
+func SetWorldStats(stats WorldStats) {
+	mu.Lock()
+	defer mu.Unlock()
+	worldStats = stats
+	SendUpdateChan <- true
+}
+
+// World returns the latest world stats, with no one online if none were collected yet
+func World() WorldStats {
+	mu.RLock()
+	defer mu.RUnlock()
+	stats := worldStats
+	stats.Players = append([]Player{}, worldStats.Players...)
+	return stats
+}
+
+// SetNextRestart sets when the next scheduled restart is, zero if none is scheduled
+func SetNextRestart(at time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+	nextRestart = at
+	SendUpdateChan <- true
+}
+
+// NextRestart returns when the next scheduled restart is, zero if none is scheduled
+func NextRestart() time.Time {
+	mu.RLock()
+	defer mu.RUnlock()
+	return nextRestart
+}
+
+// ZonePopulations returns how many players each zone process is hosting, most first
+func ZonePopulations() []ZonePopulation {
+	mu.RLock()
+	defer mu.RUnlock()
+	byApp := make(map[string]*ZonePopulation)
+	zones := make(map[string]map[string]bool)
+	for _, player := range worldStats.Players {
+		app := player.App
+		if app == "" {
+			app = player.Zone
+		}
+		pop, ok := byApp[app]
+		if !ok {
+			pop = &ZonePopulation{App: app}
+			byApp[app] = pop
+			zones[app] = make(map[string]bool)
+		}
+		pop.Players++
+		zones[app][player.Zone] = true
+	}
+
+	result := []ZonePopulation{}
+	for app, pop := range byApp {
+		names := []string{}
+		for zone := range zones[app] {
+			names = append(names, zone)
+		}
+		sort.Strings(names)
+		pop.Zones = strings.Join(names, ", ")
+		result = append(result, *pop)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Players != result[j].Players {
+			return result[i].Players > result[j].Players
+		}
+		return result[i].App < result[j].App
+	})
+	return result
+}
+
 // AppPtr is used by windows for showing a GUI of apps
 func AppPtr() map[string]*App {
 	mu.RLock()
@@ -121,9 +608,14 @@ func AppStates() *AppStateReport {
 	defer mu.RUnlock()
 
 	result := &AppStateReport{
-		States: make(map[string]AppState),
+		States:      make(map[string]AppState),
+		StaticZones: make(map[string]AppState),
 	}
 	for k, v := range apps {
+		if v.Zone != "" {
+			result.StaticZones[k] = v.Status
+			continue
+		}
 		if strings.Contains(k, "zone") {
 			result.ZoneTotal++
 			switch v.Status {
@@ -141,6 +633,12 @@ func AppStates() *AppStateReport {
 				result.ZoneSleeping++
 			case AppStateErroring:
 				result.ZoneErroring++
+			case AppStateHeld:
+				result.ZoneHeld++
+			case AppStateCrashLoop:
+				result.ZoneCrashLoop++
+			case AppStateHung:
+				result.ZoneHung++
 			}
 			continue
 		}
@@ -165,6 +663,75 @@ func AppStateString(in AppState) string {
 		return "Sleeping"
 	case AppStateErroring:
 		return "Erroring"
+	case AppStateHeld:
+		return "Held"
+	case AppStateCrashLoop:
+		return "Crash Loop"
+	case AppStateHung:
+		return "Hung"
 	}
 	return "Unknown"
 }
+
+// Report returns a snapshot of an app
+func Report(name string) (AppReport, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	app, ok := apps[name]
+	if !ok {
+		return AppReport{}, false
+	}
+	return app.report(name), true
+}
+
+// State returns the state of an app
+func State(name string) (AppState, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	app, ok := apps[name]
+	if !ok {
+		return AppStateUnknown, false
+	}
+	return app.Status, true
+}
+
+// Reports returns a snapshot of all apps, sorted by name
+func Reports() []AppReport {
+	mu.RLock()
+	defer mu.RUnlock()
+	reports := []AppReport{}
+	for name, app := range apps {
+		reports = append(reports, app.report(name))
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Name < reports[j].Name
+	})
+	return reports
+}
+
+func (a *App) report(name string) AppReport {
+	report := AppReport{
+		Name:         name,
+		PID:          a.PID,
+		ID:           a.ID,
+		State:        AppStateString(a.Status),
+		Uptime:       a.Uptime(),
+		RestartCount: a.RestartCount,
+		LastError:    a.LastError,
+		IsHeld:       a.IsHeld,
+		Alert:        a.Alert,
+		LastExit:     a.LastExit,
+		Zone:         a.Zone,
+	}
+	if len(a.Counters) > 0 {
+		report.Counters = make(map[string]int, len(a.Counters))
+		for k, v := range a.Counters {
+			report.Counters[k] = v
+		}
+	}
+	sample, ok := a.latestMetrics()
+	if ok {
+		report.Metrics = &sample
+	}
+	return report
+}
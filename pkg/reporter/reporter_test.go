@@ -0,0 +1,53 @@
+package reporter
+
+import (
+	"testing"
+)
+
+func TestAppMetricsHistory(t *testing.T) {
+	SetAppState("metricstest", AppStateRunning)
+	for i := 0; i < MetricsHistorySize+5; i++ {
+		SetAppMetrics(map[string]Metrics{"metricstest": {RSS: uint64(i)}})
+	}
+	for len(SendUpdateChan) > 0 {
+		<-SendUpdateChan
+	}
+
+	history := AppMetrics("metricstest")
+	if len(history) != MetricsHistorySize {
+		t.Fatalf("expected %d samples, got %d", MetricsHistorySize, len(history))
+	}
+	if history[0].RSS != 5 || history[len(history)-1].RSS != MetricsHistorySize+4 {
+		t.Fatalf("expected oldest 5 and newest %d, got %d and %d", MetricsHistorySize+4, history[0].RSS, history[len(history)-1].RSS)
+	}
+
+	report, _ := Report("metricstest")
+	if report.Metrics == nil || report.Metrics.RSS != MetricsHistorySize+4 {
+		t.Fatalf("expected latest sample in report, got %+v", report.Metrics)
+	}
+	latest, ok := LatestMetrics("metricstest")
+	if !ok || latest.RSS != MetricsHistorySize+4 {
+		t.Fatalf("expected latest sample, got %+v", latest)
+	}
+
+	if FormatBytes(1536) != "1.5 KB" {
+		t.Fatalf("unexpected format %s", FormatBytes(1536))
+	}
+}
+
+func TestStaticZoneStates(t *testing.T) {
+	SetAppState("poknowledge", AppStateSleeping)
+	SetAppZone("poknowledge", "poknowledge")
+	for len(SendUpdateChan) > 0 {
+		<-SendUpdateChan
+	}
+
+	state := AppStates()
+	if state.StaticZones["poknowledge"] != AppStateSleeping {
+		t.Fatalf("expected poknowledge as a sleeping static zone, got %+v", state.StaticZones)
+	}
+	_, ok := state.States["poknowledge"]
+	if ok {
+		t.Fatalf("expected static zone to not be listed as a service")
+	}
+}
@@ -0,0 +1,180 @@
+package rule
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/xackery/overseer/pkg/reporter"
+)
+
+// Action is what a rule does when a line matches
+type Action int
+
+const (
+	// ActionState moves the app to a state
+	ActionState Action = iota
+	// ActionError records the line as the app's last error, too many in a row and the app is erroring
+	ActionError
+	// ActionCounter counts matches under a name
+	ActionCounter
+	// ActionEvent records a named event
+	ActionEvent
+	// ActionAlert raises an alert on the app
+	ActionAlert
+)
+
+// Rule matches a line of app output and says what to do about it
+type Rule struct {
+	// App is the executable base name the rule applies to, such as zone, empty applies to every app
+	App     string
+	Pattern *regexp.Regexp
+	Action  Action
+	// When limits the rule to apps in these states, empty matches any state
+	When []reporter.AppState
+	// State is the state ActionState moves to
+	State reporter.AppState
+	// Name is the counter, event or alert of the rule
+	Name string
+}
+
+// Defaults are the built in rules for EQEmu apps
+func Defaults() []*Rule {
+	return []*Rule{
+		{Pattern: regexp.MustCompile(`\[Error\]`), Action: ActionError},
+		{App: "zone", Pattern: regexp.MustCompile(`Entering sleep mode`), Action: ActionState, State: reporter.AppStateSleeping},
+		{App: "zone", Pattern: regexp.MustCompile(`Zone booted successfully`), Action: ActionState, State: reporter.AppStateRunning, When: []reporter.AppState{reporter.AppStateSleeping}},
+		{App: "world", Pattern: regexp.MustCompile(`Starting EQ Network server on`), Action: ActionState, State: reporter.AppStateRunning},
+		{App: "ucs", Pattern: regexp.MustCompile(`Connected to World`), Action: ActionState, State: reporter.AppStateRunning},
+		{App: "queryserv", Pattern: regexp.MustCompile(`Connected to World`), Action: ActionState, State: reporter.AppStateRunning},
+		{App: "loginserver", Pattern: regexp.MustCompile(`Server Started`), Action: ActionState, State: reporter.AppStateRunning},
+	}
+}
+
+// Ready returns a rule that marks a starting app running once a line contains text
+func Ready(text string) *Rule {
+	return &Rule{
+		Pattern: regexp.MustCompile(regexp.QuoteMeta(text)),
+		Action:  ActionState,
+		State:   reporter.AppStateRunning,
+		When:    []reporter.AppState{reporter.AppStateStarting},
+	}
+}
+
+// Parse reads a rule from overseer.ini, written as | separated key=value fields, such as
+//
+//	rule = app=zone|when=Sleeping|state=Running|match=Zone booted successfully
+//
+// One of state, error, counter, event or alert sets the action. match must be last, so its
+// regular expression may contain |
+func Parse(value string) (*Rule, error) {
+	r := &Rule{Action: -1}
+	rest := value
+	for rest != "" {
+		field := rest
+		if !strings.HasPrefix(rest, "match=") {
+			idx := strings.Index(rest, "|")
+			if idx >= 0 {
+				field = rest[:idx]
+				rest = rest[idx+1:]
+			} else {
+				rest = ""
+			}
+		} else {
+			rest = ""
+		}
+		key, val, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return nil, fmt.Errorf("rule field %q is not key=value", field)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		val = strings.TrimSpace(val)
+		switch key {
+		case "app":
+			r.App = strings.ToLower(val)
+		case "when":
+			for _, name := range strings.Split(val, ",") {
+				state, ok := stateByName(name)
+				if !ok {
+					return nil, fmt.Errorf("rule when: unknown state %s", name)
+				}
+				r.When = append(r.When, state)
+			}
+		case "state":
+			state, ok := stateByName(val)
+			if !ok {
+				return nil, fmt.Errorf("rule state: unknown state %s", val)
+			}
+			r.Action = ActionState
+			r.State = state
+		case "error":
+			r.Action = ActionError
+		case "counter":
+			r.Action = ActionCounter
+			r.Name = val
+		case "event":
+			r.Action = ActionEvent
+			r.Name = val
+		case "alert":
+			r.Action = ActionAlert
+			r.Name = val
+		case "match":
+			pattern, err := regexp.Compile(val)
+			if err != nil {
+				return nil, fmt.Errorf("rule match: %w", err)
+			}
+			r.Pattern = pattern
+		default:
+			return nil, fmt.Errorf("unknown rule field %s", key)
+		}
+	}
+	if r.Pattern == nil {
+		return nil, fmt.Errorf("rule has no match")
+	}
+	if r.Action < 0 {
+		return nil, fmt.Errorf("rule has no state, error, counter, event or alert")
+	}
+	if (r.Action == ActionCounter || r.Action == ActionEvent || r.Action == ActionAlert) && r.Name == "" {
+		return nil, fmt.Errorf("rule counter, event or alert needs a name")
+	}
+	return r, nil
+}
+
+// Matches returns the rules that apply to a line of output from app in state, in order.
+// app is the executable base name, such as zone
+func Matches(rules []*Rule, app string, state reporter.AppState, line string) []*Rule {
+	result := []*Rule{}
+	for _, r := range rules {
+		if r.App != "" && r.App != app {
+			continue
+		}
+		if len(r.When) > 0 && !isState(r.When, state) {
+			continue
+		}
+		if !r.Pattern.MatchString(line) {
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+func isState(states []reporter.AppState, state reporter.AppState) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// stateByName returns the state named name, such as Running, ignoring case and spaces
+func stateByName(name string) (reporter.AppState, bool) {
+	name = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", ""))
+	for state := reporter.AppStateUnknown; state <= reporter.AppStateHung; state++ {
+		if strings.ToLower(strings.ReplaceAll(reporter.AppStateString(state), " ", "")) == name {
+			return state, true
+		}
+	}
+	return reporter.AppStateUnknown, false
+}
@@ -0,0 +1,79 @@
+package rule
+
+import (
+	"testing"
+
+	"github.com/xackery/overseer/pkg/reporter"
+)
+
+func TestDefaultsZoneLog(t *testing.T) {
+	rules := Defaults()
+	state := reporter.AppStateStarting
+	log := []struct {
+		line string
+		want reporter.AppState
+	}{
+		{"[Zone] [Status] Loading server configuration", reporter.AppStateStarting},
+		{"[Zone] [Status] Entering sleep mode", reporter.AppStateSleeping},
+		{"[Zone] [Zoning] Zone booted successfully zone_id [202] short_name [poknowledge]", reporter.AppStateRunning},
+		{"[Zone] [Status] Zone booted successfully zone_id [202] short_name [poknowledge]", reporter.AppStateRunning},
+		{"[Zone] [Status] Entering sleep mode", reporter.AppStateSleeping},
+	}
+	for _, entry := range log {
+		for _, r := range Matches(rules, "zone", state, entry.line) {
+			if r.Action == ActionState {
+				state = r.State
+				break
+			}
+		}
+		if state != entry.want {
+			t.Fatalf("%q: expected %s, got %s", entry.line, reporter.AppStateString(entry.want), reporter.AppStateString(state))
+		}
+	}
+
+	matches := Matches(rules, "world", reporter.AppStateRunning, "[World] [Error] Database connection lost")
+	if len(matches) != 1 || matches[0].Action != ActionError {
+		t.Fatalf("expected an error match, got %+v", matches)
+	}
+	matches = Matches(rules, "world", reporter.AppStateStarting, "[Zone] [Status] Entering sleep mode")
+	if len(matches) != 0 {
+		t.Fatalf("expected zone rules to not apply to world, got %+v", matches)
+	}
+}
+
+func TestParse(t *testing.T) {
+	r, err := Parse("app=zone|when=Sleeping,Running|counter=deaths|match=(died|was slain)")
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	if r.App != "zone" || r.Action != ActionCounter || r.Name != "deaths" || len(r.When) != 2 {
+		t.Fatalf("unexpected rule: %+v", r)
+	}
+	if len(Matches([]*Rule{r}, "zone", reporter.AppStateRunning, "Xackery was slain by a gnoll")) != 1 {
+		t.Fatalf("expected match with | in the regular expression")
+	}
+	if len(Matches([]*Rule{r}, "zone", reporter.AppStateStarting, "Xackery died")) != 0 {
+		t.Fatalf("expected no match while starting")
+	}
+
+	r, err = Parse("state=Crash Loop|match=Segmentation fault")
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	if r.Action != ActionState || r.State != reporter.AppStateCrashLoop {
+		t.Fatalf("unexpected rule: %+v", r)
+	}
+
+	for _, bad := range []string{
+		"state=Running",
+		"match=ok",
+		"state=Bored|match=ok",
+		"alert=|match=ok",
+		"state=Running|match=(",
+	} {
+		_, err = Parse(bad)
+		if err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
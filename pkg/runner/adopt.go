@@ -0,0 +1,184 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+	"github.com/xackery/overseer/pkg/flog"
+)
+
+const (
+	// adoptPollInterval is how often an adopted process is checked for exiting
+	adoptPollInterval = time.Second
+)
+
+// AdoptedRunner tracks a process left running by a previous overseer. Its output can't be
+// read, so it is only watched until it exits
+type AdoptedRunner struct {
+	displayName string
+	pid         int
+	mu          sync.Mutex
+	exited      chan struct{}
+	isRunning   bool
+	isKilled    bool
+	startedAt   time.Time
+	exit        ExitInfo
+}
+
+// NewAdopted creates a runner for an already running pid
+func NewAdopted(displayName string, pid int) *AdoptedRunner {
+	return &AdoptedRunner{
+		displayName: displayName,
+		pid:         pid,
+		exit:        ExitInfo{Code: -1},
+	}
+}
+
+// Start confirms the adopted process is still running
+func (r *AdoptedRunner) Start(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.isRunning {
+		return fmt.Errorf("already running")
+	}
+	if !pidRunning(r.pid) {
+		return fmt.Errorf("adopt pid %d: not running", r.pid)
+	}
+	flog.Printf("[runner][%s] adopted pid %d\n", r.displayName, r.pid)
+	r.isRunning = true
+	r.exited = make(chan struct{})
+	r.startedAt = time.Now()
+	r.exit = ExitInfo{Code: -1}
+	return nil
+}
+
+// Wait blocks until the adopted process exits. Its exit code is unknown, so an error is always returned
+func (r *AdoptedRunner) Wait() error {
+	r.mu.Lock()
+	exited := r.exited
+	isRunning := r.isRunning
+	r.mu.Unlock()
+	if !isRunning {
+		return fmt.Errorf("not running")
+	}
+
+	for pidRunning(r.pid) {
+		time.Sleep(adoptPollInterval)
+	}
+
+	r.mu.Lock()
+	r.isRunning = false
+	r.exit = ExitInfo{Code: -1, IsKilled: r.isKilled, Runtime: time.Since(r.startedAt)}
+	r.mu.Unlock()
+	close(exited)
+	flog.Printf("[runner][%s] adopted pid %d exited\n", r.displayName, r.pid)
+	return fmt.Errorf("wait: adopted pid %d exited, status unknown", r.pid)
+}
+
+// Stop interrupts the adopted process, and kills its process group if it has not exited after grace
+func (r *AdoptedRunner) Stop(grace time.Duration) error {
+	r.mu.Lock()
+	exited := r.exited
+	isRunning := r.isRunning
+	r.mu.Unlock()
+	if !isRunning {
+		return nil
+	}
+
+	isKilled, err := stopPID(r.displayName, r.pid, grace, exited)
+	if isKilled {
+		r.mu.Lock()
+		r.isKilled = true
+		r.mu.Unlock()
+	}
+	return err
+}
+
+// PID returns the adopted pid, or 0 once it has exited
+func (r *AdoptedRunner) PID() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.isRunning {
+		return 0
+	}
+	return r.pid
+}
+
+// ID returns the pid as a string, or empty if not running
+func (r *AdoptedRunner) ID() string {
+	pid := r.PID()
+	if pid == 0 {
+		return ""
+	}
+	return strconv.Itoa(pid)
+}
+
+// ExitCode is always -1, the exit code of a process that isn't a child can't be read
+func (r *AdoptedRunner) ExitCode() int {
+	return -1
+}
+
+// ExitInfo returns how the adopted process exited, as far as is known
+func (r *AdoptedRunner) ExitInfo() ExitInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.exit
+}
+
+// Reap stops a process left running by a previous overseer, killing its process group if it
+// has not exited after grace
+func Reap(displayName string, pid int, grace time.Duration) error {
+	if !pidRunning(pid) {
+		return nil
+	}
+	exited := make(chan struct{})
+	go func() {
+		for pidRunning(pid) {
+			time.Sleep(100 * time.Millisecond)
+		}
+		close(exited)
+	}()
+	_, err := stopPID(displayName, pid, grace, exited)
+	return err
+}
+
+// stopPID interrupts pid, and kills its process group if exited is not closed after grace
+func stopPID(displayName string, pid int, grace time.Duration, exited chan struct{}) (bool, error) {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false, fmt.Errorf("find process: %w", err)
+	}
+	flog.Printf("[runner][%s] stopping pid %d, grace period %s\n", displayName, pid, grace)
+	err = p.Signal(os.Interrupt)
+	if err == nil {
+		select {
+		case <-exited:
+			return false, nil
+		case <-time.After(grace):
+		}
+	}
+	flog.Printf("[runner][%s] pid %d still running, killing process group\n", displayName, pid)
+	err = killGroup(p)
+	if err != nil && err != os.ErrProcessDone {
+		return true, fmt.Errorf("kill: %w", err)
+	}
+	return true, nil
+}
+
+// pidRunning returns true if pid exists and is not a zombie
+func pidRunning(pid int) bool {
+	p, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return false
+	}
+	status, err := p.Status()
+	if err == nil && len(status) > 0 && status[0] == process.Zombie {
+		return false
+	}
+	return true
+}
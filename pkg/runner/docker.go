@@ -1,80 +1,261 @@
 package runner
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"os"
-	"os/exec"
+	"io"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/xackery/overseer/pkg/flog"
+)
+
+const (
+	// dockerServerPath is where wdPath is mounted inside a container
+	dockerServerPath = "/eqemu/server"
+	// dockerBinPath is where exePath is mounted inside a container
+	dockerBinPath = "/eqemu/bin"
 )
 
 // DockerRunner handles polling and running a docker container
 type DockerRunner struct {
-	outChan  chan (string)
-	doneChan chan (error)
-	name     string
-	args     []string
-	cmd      *exec.Cmd
+	cli         *client.Client
+	outChan     chan (string)
+	displayName string
+	image       string
+	network     string
+	wdPath      string
+	exePath     string
+	name        string
+	args        []string
+	env         []string
+	mu          sync.Mutex
+	ctx         context.Context
+	containerID string
+	startedAt   time.Time
+	isStopping  bool // true once Stop was called, so a kill after the grace period can be told apart
+	exit        ExitInfo
 }
 
-func NewDocker(outChan chan (string), doneChan chan (error), name string, args ...string) *DockerRunner {
+// NewDocker creates a runner that runs exePath/name inside image, on network, with wdPath
+// and exePath bind mounted so the host copy of the server is used
+func NewDocker(cli *client.Client, outChan chan (string), displayName string, image string, network string, wdPath string, exePath string, name string, args ...string) *DockerRunner {
 	return &DockerRunner{
-		outChan:  outChan,
-		doneChan: doneChan,
-		name:     name,
-		args:     args,
+		cli:         cli,
+		outChan:     outChan,
+		displayName: displayName,
+		image:       image,
+		network:     network,
+		wdPath:      wdPath,
+		exePath:     exePath,
+		name:        name,
+		args:        args,
+		exit:        ExitInfo{Code: -1},
 	}
 }
 
-func (r *DockerRunner) Start() {
-	if r.cmd != nil {
-		return
-	}
-	r.cmd = exec.Command(r.name, r.args...)
-	err := r.run()
-	r.cmd = nil
-	r.doneChan <- err
+// SetEnv sets KEY=VALUE pairs in the container environment
+func (r *DockerRunner) SetEnv(env []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.env = env
+}
+
+// ContainerName returns the docker container name used for an app
+func ContainerName(displayName string) string {
+	return "overseer-" + strings.ToLower(displayName)
 }
 
-func (r *DockerRunner) run() error {
-	var err error
+// Start creates and starts the container, streaming its logs to outChan
+func (r *DockerRunner) Start(ctx context.Context) error {
+	r.mu.Lock()
+	if r.containerID != "" {
+		r.mu.Unlock()
+		return fmt.Errorf("already running")
+	}
+	r.mu.Unlock()
+
+	containerName := ContainerName(r.displayName)
+
+	// a container left over from a crashed overseer would block the name
+	err := r.cli.ContainerRemove(ctx, containerName, types.ContainerRemoveOptions{Force: true})
+	if err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("remove stale container: %w", err)
+	}
+
+	cfg := &container.Config{
+		Image:      r.image,
+		Cmd:        append([]string{dockerBinPath + "/" + r.name}, r.args...),
+		WorkingDir: dockerServerPath,
+		Env:        r.env,
+		Hostname:   r.displayName,
+		Labels: map[string]string{
+			"overseer.app": r.displayName,
+		},
+	}
+	hostCfg := &container.HostConfig{
+		Binds: []string{
+			r.wdPath + ":" + dockerServerPath,
+			r.exePath + ":" + dockerBinPath,
+		},
+		NetworkMode: container.NetworkMode(r.network),
+	}
+	netCfg := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			r.network: {Aliases: []string{r.displayName}},
+		},
+	}
 
-	cwd, err := os.Getwd()
+	flog.Printf("[docker][%s] creating container %s from image %s on network %s\n", r.displayName, containerName, r.image, r.network)
+	resp, err := r.cli.ContainerCreate(ctx, cfg, hostCfg, netCfg, nil, containerName)
 	if err != nil {
-		return fmt.Errorf("getwd: %w", err)
+		return fmt.Errorf("container create: %w", err)
 	}
 
-	r.cmd.Dir = cwd
+	err = r.cli.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{})
+	if err != nil {
+		r.remove(resp.ID)
+		return fmt.Errorf("container start: %w", err)
+	}
 
-	stdout, err := r.cmd.StdoutPipe()
+	logs, err := r.cli.ContainerLogs(ctx, resp.ID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
 	if err != nil {
-		return fmt.Errorf("stdout pipe: %w", err)
+		r.remove(resp.ID)
+		return fmt.Errorf("container logs: %w", err)
 	}
 
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
 	go func() {
-		scanner := bufio.NewScanner(stdout)
-		scanner.Split(bufio.ScanLines)
-		for scanner.Scan() {
-			r.outChan <- scanner.Text()
-		}
+		defer logs.Close()
+		// containers run without a tty, so stdout and stderr are multiplexed
+		_, err := stdcopy.StdCopy(stdoutWriter, stderrWriter, logs)
+		stdoutWriter.CloseWithError(err)
+		stderrWriter.CloseWithError(err)
 	}()
+	go scanLines(stdoutReader, "", r.outChan)
+	go scanLines(stderrReader, StderrPrefix, r.outChan)
 
-	err = r.cmd.Start()
-	if err != nil {
-		return fmt.Errorf("start: %w", err)
+	r.mu.Lock()
+	r.ctx = ctx
+	r.containerID = resp.ID
+	r.startedAt = time.Now()
+	r.isStopping = false
+	r.exit = ExitInfo{Code: -1}
+	r.mu.Unlock()
+	return nil
+}
+
+// Wait blocks until the container exits, then removes it
+func (r *DockerRunner) Wait() error {
+	r.mu.Lock()
+	ctx := r.ctx
+	id := r.containerID
+	r.mu.Unlock()
+	if id == "" {
+		return fmt.Errorf("not running")
 	}
 
-	err = r.cmd.Wait()
+	err := r.wait(ctx, id)
 	if err != nil {
-		return fmt.Errorf("wait: %w", err)
+		flog.Printf("[docker][%s] finished with error: %s\n", r.displayName, err)
 	}
 
+	r.remove(id)
+	r.mu.Lock()
+	r.containerID = ""
+	r.mu.Unlock()
+	flog.Printf("[docker][%s] done\n", r.displayName)
+	return err
+}
+
+func (r *DockerRunner) wait(ctx context.Context, id string) error {
+	statusChan, errChan := r.cli.ContainerWait(ctx, id, container.WaitConditionNotRunning)
+	select {
+	case err := <-errChan:
+		return fmt.Errorf("container wait: %w", err)
+	case status := <-statusChan:
+		if status.Error != nil {
+			return fmt.Errorf("container wait: %s", status.Error.Message)
+		}
+		r.mu.Lock()
+		r.exit = containerExit(int(status.StatusCode), time.Since(r.startedAt))
+		// docker sends SIGKILL once the stop timeout passes
+		r.exit.IsKilled = r.isStopping && r.exit.Signal == syscall.SIGKILL.String()
+		r.mu.Unlock()
+		if status.StatusCode != 0 {
+			return fmt.Errorf("wait: exit status %d", status.StatusCode)
+		}
+	}
+	flog.Printf("[docker][%s] self exit\n", r.displayName)
 	return nil
 }
 
-func (r *DockerRunner) Stop() error {
-	if r.cmd == nil {
-		return nil
+// remove force removes a container, ctx may already be cancelled so a fresh one is used
+func (r *DockerRunner) remove(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := r.cli.ContainerRemove(ctx, id, types.ContainerRemoveOptions{Force: true})
+	if err != nil && !errdefs.IsNotFound(err) {
+		flog.Printf("[docker][%s] remove: %s\n", r.displayName, err)
 	}
+}
 
+// Stop asks docker to stop the container, which kills it if it has not exited after grace
+func (r *DockerRunner) Stop(grace time.Duration) error {
+	r.mu.Lock()
+	id := r.containerID
+	r.isStopping = true
+	r.mu.Unlock()
+	if id == "" {
+		return nil
+	}
+	flog.Printf("[docker][%s] stopping, grace period %s\n", r.displayName, grace)
+	timeout := int(grace.Seconds())
+	ctx, cancel := context.WithTimeout(context.Background(), grace+5*time.Second)
+	defer cancel()
+	err := r.cli.ContainerStop(ctx, id, container.StopOptions{Timeout: &timeout})
+	if err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("container stop: %w", err)
+	}
 	return nil
 }
+
+// PID returns 0, containers are identified by ID
+func (r *DockerRunner) PID() int {
+	return 0
+}
+
+// ID returns the short container id, or empty if not running
+func (r *DockerRunner) ID() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.containerID) > 12 {
+		return r.containerID[:12]
+	}
+	return r.containerID
+}
+
+// ExitCode returns the exit code of the last run
+func (r *DockerRunner) ExitCode() int {
+	return r.ExitInfo().Code
+}
+
+// ExitInfo returns how the last run exited
+func (r *DockerRunner) ExitInfo() ExitInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.exit
+}
@@ -2,11 +2,20 @@ package runner
 
 import (
 	"context"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
 )
 
@@ -54,3 +63,153 @@ func TestDockerContainerList(t *testing.T) {
 		fmt.Printf("%s %s\n", container.ID[:10], container.Image)
 	}
 }
+
+// fakeDocker mimics the docker engine api endpoints DockerRunner uses
+type fakeDocker struct {
+	mu       sync.Mutex
+	created  *container.Config
+	host     *container.HostConfig
+	calls    []string
+	stopped  chan struct{}
+	exitCode int
+}
+
+func (f *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path[strings.Index(r.URL.Path[1:], "/")+1:] // strip /v1.43
+	f.mu.Lock()
+	f.calls = append(f.calls, r.Method+" "+path)
+	f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/containers/overseer-"):
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"no such container"}`)
+	case r.Method == http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	case path == "/containers/create":
+		body := struct {
+			*container.Config
+			HostConfig *container.HostConfig
+		}{}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.created = body.Config
+		f.host = body.HostConfig
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"Id":"0123456789abcdef0123"}`)
+	case strings.HasSuffix(path, "/start"):
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasSuffix(path, "/logs"):
+		w.Header().Set("Content-Type", "application/vnd.docker.multiplexed-stream")
+		w.WriteHeader(http.StatusOK)
+		writeFrame(w, 1, "Starting EQ Network server on 9000\n")
+		writeFrame(w, 2, "[Error] something broke\n")
+	case strings.HasSuffix(path, "/wait"):
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		select {
+		case <-f.stopped:
+		case <-r.Context().Done():
+			return
+		}
+		fmt.Fprintf(w, `{"StatusCode":%d}`, f.exitCode)
+	case strings.HasSuffix(path, "/stop"):
+		close(f.stopped)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func writeFrame(w io.Writer, stream byte, msg string) {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(msg)))
+	w.Write(header)
+	w.Write([]byte(msg))
+}
+
+func TestDockerRunner(t *testing.T) {
+	fake := &fakeDocker{stopped: make(chan struct{}), exitCode: 3}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+strings.TrimPrefix(srv.URL, "http://")), client.WithVersion("1.43"))
+	if err != nil {
+		t.Fatalf("new client: %s", err)
+	}
+
+	outChan := make(chan string, 10)
+	doneChan := make(chan error, 1)
+	r := NewDocker(cli, outChan, "world", "debian:stable-slim", "eqemu", "/srv/server", "/srv/bin", "world", "--flag")
+
+	err = r.Start(context.Background())
+	if err != nil {
+		t.Fatalf("start: %s", err)
+	}
+	go func() {
+		doneChan <- r.Wait()
+	}()
+
+	// stdout and stderr are scanned separately, so lines may arrive in any order
+	lines := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case line := <-outChan:
+			lines[line] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for output, got %v", lines)
+		}
+	}
+	for _, want := range []string{"Starting EQ Network server on 9000", StderrPrefix + "[Error] something broke"} {
+		if !lines[want] {
+			t.Fatalf("expected line %q, got %v", want, lines)
+		}
+	}
+
+	if r.ID() != "0123456789ab" {
+		t.Fatalf("expected short container id, got %q", r.ID())
+	}
+
+	fake.mu.Lock()
+	created := fake.created
+	host := fake.host
+	fake.mu.Unlock()
+	if created.Image != "debian:stable-slim" {
+		t.Fatalf("unexpected image %s", created.Image)
+	}
+	if strings.Join(created.Cmd, " ") != "/eqemu/bin/world --flag" {
+		t.Fatalf("unexpected cmd %v", created.Cmd)
+	}
+	if string(host.NetworkMode) != "eqemu" {
+		t.Fatalf("unexpected network %s", host.NetworkMode)
+	}
+
+	err = r.Stop(10 * time.Second)
+	if err != nil {
+		t.Fatalf("stop: %s", err)
+	}
+
+	select {
+	case err = <-doneChan:
+		if err == nil || !strings.Contains(err.Error(), "exit status 3") {
+			t.Fatalf("expected exit status 3, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for container to finish")
+	}
+	if r.ID() != "" {
+		t.Fatalf("expected id to be cleared after exit")
+	}
+	if r.ExitCode() != 3 {
+		t.Fatalf("expected exit code 3, got %d", r.ExitCode())
+	}
+	if r.ExitInfo().Signal != "" {
+		t.Fatalf("expected no signal, got %s", r.ExitInfo().Signal)
+	}
+}
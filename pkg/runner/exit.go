@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	// StderrPrefix is put in front of every line an app writes to stderr
+	StderrPrefix = "[stderr] "
+)
+
+// ExitInfo describes how an app exited
+type ExitInfo struct {
+	// Code is the exit code, or -1 if killed by a signal
+	Code int
+	// Signal is the signal that terminated the app, empty if it exited on its own
+	Signal       string
+	IsCoreDumped bool
+	// IsKilled is true when the app did not exit within its stop grace period and was killed
+	IsKilled bool
+	Runtime  time.Duration
+}
+
+// String returns a short description, such as "exit code 1 after 5s"
+func (e ExitInfo) String() string {
+	msg := fmt.Sprintf("exit code %d", e.Code)
+	if e.Signal != "" {
+		msg = "signal " + e.Signal
+	}
+	if e.IsCoreDumped {
+		msg += " (core dumped)"
+	}
+	if e.IsKilled {
+		msg += " (forced kill)"
+	}
+	return msg + " after " + e.Runtime.Round(time.Second).String()
+}
+
+// processExit builds exit info from a finished process
+func processExit(state *os.ProcessState, runtime time.Duration) ExitInfo {
+	info := ExitInfo{Code: -1, Runtime: runtime}
+	if state == nil {
+		return info
+	}
+	info.Code = state.ExitCode()
+	ws, ok := state.Sys().(syscall.WaitStatus)
+	if ok && ws.Signaled() {
+		info.Signal = ws.Signal().String()
+		info.IsCoreDumped = ws.CoreDump()
+	}
+	return info
+}
+
+// containerExit builds exit info from a container status code, docker reports death by signal as 128+signal
+func containerExit(code int, runtime time.Duration) ExitInfo {
+	info := ExitInfo{Code: code, Runtime: runtime}
+	if code > 128 && code < 160 {
+		info.Signal = syscall.Signal(code - 128).String()
+	}
+	return info
+}
+
+// scanLines sends each line read from r to outChan, with prefix in front
+func scanLines(r io.Reader, prefix string, outChan chan string) {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		outChan <- prefix + scanner.Text()
+	}
+}
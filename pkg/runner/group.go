@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package runner
+
+import (
+	"os"
+	"syscall"
+)
+
+// killGroup kills a process and everything in its process group
+func killGroup(p *os.Process) error {
+	err := syscall.Kill(-p.Pid, syscall.SIGKILL)
+	if err == syscall.ESRCH {
+		return os.ErrProcessDone
+	}
+	return err
+}
@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package runner
+
+import "os"
+
+// killGroup kills a process, windows has no process groups to signal
+func killGroup(p *os.Process) error {
+	return p.Kill()
+}
@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/xackery/overseer/pkg/flog"
+)
+
+const (
+	// onceMaxLines is how many trailing output lines RunOnce keeps
+	onceMaxLines = 100
+)
+
+// RunOnce runs a process to completion, returning the last lines of its combined output. env
+// adds KEY=VALUE pairs to the environment the process inherits
+func RunOnce(ctx context.Context, displayName string, wdPath string, exePath string, env []string, name string, args ...string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, exePath+"/"+name, args...)
+	cmd.Dir = wdPath
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.SysProcAttr = newProcAttr()
+
+	r, w := io.Pipe()
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	lines := []string{}
+	scanDone := make(chan struct{})
+	go func() {
+		defer close(scanDone)
+		scanner := bufio.NewScanner(r)
+		scanner.Split(bufio.ScanLines)
+		for scanner.Scan() {
+			line := scanner.Text()
+			flog.Printf("[runner][%s] %s\n", displayName, line)
+			lines = append(lines, line)
+			if len(lines) > onceMaxLines {
+				lines = lines[1:]
+			}
+		}
+	}()
+
+	flog.Printf("[runner][%s] running once from wdPath: '%s', exePath: '%s'\n", displayName, wdPath, exePath)
+	err := cmd.Run()
+	w.Close()
+	<-scanDone
+	if err != nil {
+		return lines, fmt.Errorf("run: %w", err)
+	}
+	flog.Printf("[runner][%s] finished\n", displayName)
+	return lines, nil
+}
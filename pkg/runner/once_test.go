@@ -0,0 +1,44 @@
+package runner
+
+import (
+	"context"
+	"runtime"
+	"testing"
+)
+
+func TestRunOnce(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping test; requires sh")
+	}
+
+	ctx := context.Background()
+	lines, err := RunOnce(ctx, "test", ".", "/bin", nil, "sh", "-c", "echo one; echo two 1>&2")
+	if err != nil {
+		t.Fatalf("run once: %s", err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %v", len(lines), lines)
+	}
+
+	lines, err = RunOnce(ctx, "test", ".", "/bin", nil, "sh", "-c", "echo broken; exit 3")
+	if err == nil {
+		t.Fatalf("expected error on non-zero exit")
+	}
+	if len(lines) != 1 || lines[0] != "broken" {
+		t.Fatalf("expected output to be captured, got %v", lines)
+	}
+}
+
+func TestRunOnceEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping test; requires sh")
+	}
+
+	lines, err := RunOnce(context.Background(), "test", ".", "/bin", []string{"ONCE_VALUE=a=b"}, "sh", "-c", "echo $ONCE_VALUE")
+	if err != nil {
+		t.Fatalf("run once: %s", err)
+	}
+	if len(lines) != 1 || lines[0] != "a=b" {
+		t.Fatalf("expected env to be set, got %v", lines)
+	}
+}
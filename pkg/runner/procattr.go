@@ -6,5 +6,6 @@ package runner
 import "syscall"
 
 func newProcAttr() *syscall.SysProcAttr {
-	return &syscall.SysProcAttr{}
+	// each app gets its own process group, so anything it spawns can be killed with it
+	return &syscall.SysProcAttr{Setpgid: true}
 }
@@ -1,44 +1,60 @@
 package runner
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/xackery/overseer/pkg/flog"
 )
 
-// Runner handles running and polling output of a process
+// ProcessRunner handles running and polling output of a process
 type ProcessRunner struct {
 	outChan     chan (string)
-	doneChan    chan (error)
 	displayName string
 	wdPath      string
 	exePath     string
 	name        string
 	args        []string
+	env         []string
+	mu          sync.Mutex
 	cmd         *exec.Cmd
+	scanDone    chan struct{}
+	exited      chan struct{}
+	startedAt   time.Time
+	isKilled    bool // true once Stop had to kill after the grace period
+	exit        ExitInfo
 }
 
-func NewProcess(outChan chan (string), doneChan chan (error), displayName string, wdPath string, exePath string, name string, args ...string) *ProcessRunner {
+func NewProcess(outChan chan (string), displayName string, wdPath string, exePath string, name string, args ...string) *ProcessRunner {
 	return &ProcessRunner{
 		outChan:     outChan,
-		doneChan:    doneChan,
 		displayName: displayName,
 		wdPath:      wdPath,
 		exePath:     exePath,
 		name:        name,
 		args:        args,
+		exit:        ExitInfo{Code: -1},
 	}
 }
 
-// Start starts the process
-func (r *ProcessRunner) Start(ctx context.Context) {
+// SetEnv adds KEY=VALUE pairs to the environment the process inherits
+func (r *ProcessRunner) SetEnv(env []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.env = env
+}
+
+// Start starts the process. It is not killed when ctx is done, Stop ends it gracefully
+func (r *ProcessRunner) Start(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if r.cmd != nil {
-		flog.Printf("[runner][%s] already running\n", r.displayName)
-		return
+		return fmt.Errorf("already running")
 	}
 
 	fullCmd := r.name
@@ -47,69 +63,123 @@ func (r *ProcessRunner) Start(ctx context.Context) {
 	}
 
 	flog.Printf("[runner][%s] priming wdPath: '%s', exePath: '%s', exeCommand: '%s'\n", r.displayName, r.wdPath, r.exePath, fullCmd)
-	r.cmd = exec.CommandContext(ctx, r.exePath+"/"+r.name, r.args...)
+	cmd := exec.Command(r.exePath+"/"+r.name, r.args...)
+	cmd.Dir = r.wdPath
+	if len(r.env) > 0 {
+		cmd.Env = append(os.Environ(), r.env...)
+	}
+	// don't pop up window for new process
+	cmd.SysProcAttr = newProcAttr()
 
-	r.cmd.Dir = r.wdPath
-	err := r.run()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		if err.Error() != "wait: signal: killed" {
-			flog.Printf("[runner][%s] finished with error: %s\n", r.displayName, err)
-		}
+		return fmt.Errorf("stderr pipe: %w", err)
 	}
-	r.cmd = nil
-	flog.Printf("[runner][%s] done\n", r.displayName)
-	r.doneChan <- err
-}
-
-func (r *ProcessRunner) run() error {
-	var err error
 
-	stdout, err := r.cmd.StdoutPipe()
+	flog.Printf("[runner][%s] starting process\n", r.displayName)
+	err = cmd.Start()
 	if err != nil {
-		return fmt.Errorf("stdout pipe: %w", err)
+		return fmt.Errorf("start %s: %w", r.name, err)
 	}
 
+	scanDone := make(chan struct{})
+	stderrDone := make(chan struct{})
 	go func() {
-		scanner := bufio.NewScanner(stdout)
-		scanner.Split(bufio.ScanLines)
-		for scanner.Scan() {
-			r.outChan <- scanner.Text()
-		}
+		defer close(stderrDone)
+		scanLines(stderr, StderrPrefix, r.outChan)
+	}()
+	go func() {
+		defer close(scanDone)
+		scanLines(stdout, "", r.outChan)
+		<-stderrDone
 	}()
 
-	// don't pop up window for new process
-	r.cmd.SysProcAttr = newProcAttr()
+	r.cmd = cmd
+	r.scanDone = scanDone
+	r.exited = make(chan struct{})
+	r.startedAt = time.Now()
+	r.isKilled = false
+	r.exit = ExitInfo{Code: -1}
+	return nil
+}
 
-	flog.Printf("[runner][%s] starting process\n", r.displayName)
-	err = r.cmd.Start()
-	if err != nil {
-		return fmt.Errorf("start %+v: %w", r.cmd, err)
+// Wait blocks until the process exits
+func (r *ProcessRunner) Wait() error {
+	r.mu.Lock()
+	cmd := r.cmd
+	scanDone := r.scanDone
+	exited := r.exited
+	r.mu.Unlock()
+	if cmd == nil {
+		return fmt.Errorf("not running")
 	}
 
 	flog.Printf("[runner][%s] wait process\n", r.displayName)
-	err = r.cmd.Wait()
+	// output must be fully read before wait closes the pipe
+	<-scanDone
+	err := cmd.Wait()
+
+	r.mu.Lock()
+	r.exit = processExit(cmd.ProcessState, time.Since(r.startedAt))
+	r.exit.IsKilled = r.isKilled
+	r.cmd = nil
+	exit := r.exit
+	r.mu.Unlock()
+	close(exited)
+
 	if err != nil {
+		flog.Printf("[runner][%s] finished with error: %s (%s)\n", r.displayName, err, exit)
 		return fmt.Errorf("wait: %w", err)
 	}
-
 	flog.Printf("[runner][%s] self exit\n", r.displayName)
 	return nil
 }
 
-func (r *ProcessRunner) Stop() error {
-	if r.cmd == nil {
+// Stop interrupts the process, and kills its process group if it has not exited after grace
+func (r *ProcessRunner) Stop(grace time.Duration) error {
+	r.mu.Lock()
+	cmd := r.cmd
+	exited := r.exited
+	r.mu.Unlock()
+	if cmd == nil || cmd.Process == nil {
 		return nil
 	}
-	flog.Printf("[runner][%s] stopping\n", r.displayName)
-	err := r.cmd.Process.Signal(os.Interrupt)
+
+	flog.Printf("[runner][%s] stopping, grace period %s\n", r.displayName, grace)
+	err := cmd.Process.Signal(os.Interrupt)
 	if err != nil {
-		return fmt.Errorf("signal: %w", err)
+		// windows can't interrupt, so kill right away
+		flog.Printf("[runner][%s] interrupt: %s, killing\n", r.displayName, err)
+		return r.kill(cmd)
+	}
+
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(grace):
 	}
+	flog.Printf("[runner][%s] still running after %s, killing process group\n", r.displayName, grace)
+	return r.kill(cmd)
+}
 
+func (r *ProcessRunner) kill(cmd *exec.Cmd) error {
+	r.mu.Lock()
+	r.isKilled = true
+	r.mu.Unlock()
+	err := killGroup(cmd.Process)
+	if err != nil && err != os.ErrProcessDone {
+		return fmt.Errorf("kill: %w", err)
+	}
 	return nil
 }
 
 func (r *ProcessRunner) PID() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if r.cmd == nil {
 		return 0
 	}
@@ -118,3 +188,24 @@ func (r *ProcessRunner) PID() int {
 	}
 	return r.cmd.Process.Pid
 }
+
+// ID returns the pid as a string, or empty if not running
+func (r *ProcessRunner) ID() string {
+	pid := r.PID()
+	if pid == 0 {
+		return ""
+	}
+	return strconv.Itoa(pid)
+}
+
+// ExitCode returns the exit code of the last run
+func (r *ProcessRunner) ExitCode() int {
+	return r.ExitInfo().Code
+}
+
+// ExitInfo returns how the last run exited
+func (r *ProcessRunner) ExitInfo() ExitInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.exit
+}
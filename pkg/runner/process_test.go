@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestProcessRunnerExit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping test; requires sh")
+	}
+
+	outChan := make(chan string, 10)
+	r := NewProcess(outChan, "test", ".", "/bin", "sh", "-c", "echo out; echo err 1>&2; kill -TERM $$")
+	err := r.Start(context.Background())
+	if err != nil {
+		t.Fatalf("start: %s", err)
+	}
+	err = r.Wait()
+	if err == nil {
+		t.Fatalf("expected error when killed by signal")
+	}
+	close(outChan)
+
+	lines := map[string]bool{}
+	for line := range outChan {
+		lines[line] = true
+	}
+	if !lines["out"] || !lines[StderrPrefix+"err"] {
+		t.Fatalf("expected tagged stdout and stderr, got %v", lines)
+	}
+
+	exit := r.ExitInfo()
+	if exit.Code != -1 {
+		t.Fatalf("expected exit code -1, got %d", exit.Code)
+	}
+	if exit.Signal != "terminated" {
+		t.Fatalf("expected terminated signal, got %q", exit.Signal)
+	}
+	if exit.Runtime <= 0 || exit.Runtime > 5*time.Second {
+		t.Fatalf("unexpected runtime %s", exit.Runtime)
+	}
+}
+
+func TestProcessRunnerStopKillsGroup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping test; requires sh")
+	}
+
+	outChan := make(chan string, 10)
+	// the shell ignores interrupt, and its child keeps stdout open until the group is killed
+	r := NewProcess(outChan, "test", ".", "/bin", "sh", "-c", "trap '' INT; echo ready; sleep 30; echo done")
+	err := r.Start(context.Background())
+	if err != nil {
+		t.Fatalf("start: %s", err)
+	}
+	doneChan := make(chan error, 1)
+	go func() {
+		doneChan <- r.Wait()
+	}()
+	<-outChan
+
+	err = r.Stop(100 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("stop: %s", err)
+	}
+	select {
+	case <-doneChan:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for process group to die")
+	}
+
+	exit := r.ExitInfo()
+	if !exit.IsKilled {
+		t.Fatalf("expected forced kill, got %s", exit)
+	}
+	if exit.Signal != "killed" {
+		t.Fatalf("expected killed signal, got %q", exit.Signal)
+	}
+}
@@ -1,8 +1,24 @@
 package runner
 
+import (
+	"context"
+	"time"
+)
+
 // Runner wraps an executable and provides a way manage it's output
 type Runner interface {
-	Start()
-	Stop() error
+	// Start launches the app, returning once it is running or failed to launch
+	Start(ctx context.Context) error
+	// Wait blocks until the app exits, returning why it exited
+	Wait() error
+	// Stop asks the app to exit, killing it if it is still running after grace
+	Stop(grace time.Duration) error
+	// PID is the process id, or 0 if not running or not a local process
 	PID() int
+	// ID identifies what is running, a pid for processes or a container id for docker
+	ID() string
+	// ExitCode is the exit code of the last run, or -1 if it has not exited or was killed by a signal
+	ExitCode() int
+	// ExitInfo describes how the last run exited
+	ExitInfo() ExitInfo
 }
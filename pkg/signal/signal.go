@@ -3,6 +3,7 @@ package signal
 import (
 	"context"
 	"sync"
+	"time"
 )
 
 var (
@@ -25,9 +26,19 @@ func Ctx() context.Context {
 	return ctx
 }
 
-// WaitWorker waits for all workers to finish.
-func WaitWorker() {
-	wg.Wait()
+// WaitWorker waits for all workers to finish, returns false if timeout passed first.
+func WaitWorker(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
 }
 
 // AddWorker adds a worker.
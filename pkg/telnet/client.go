@@ -0,0 +1,321 @@
+package telnet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xackery/overseer/pkg/config"
+	"github.com/xackery/overseer/pkg/flog"
+	"github.com/ziutek/telnet"
+)
+
+const (
+	// DefaultTimeout is how long a command has to answer when its context has no deadline
+	DefaultTimeout = 5 * time.Second
+	// DefaultKeepalive is how often an idle connection is checked
+	DefaultKeepalive = time.Minute
+)
+
+var (
+	// ErrDisabled is returned by NewClientFromConfig when world's telnet console is turned off
+	ErrDisabled = errors.New("telnet is disabled in eqemu_config.json")
+	// ErrNoClient is returned by requests on a nil client, when world has no usable console
+	ErrNoClient = errors.New("no world telnet console configured")
+)
+
+// Client is a connection to world's telnet console. It connects on first use, reconnects
+// after a failure, and is safe to use from multiple goroutines, one request at a time
+type Client struct {
+	mu       sync.Mutex
+	addr     string
+	username string
+	password string
+	// Keepalive is how often an idle connection is checked, 0 disables it
+	Keepalive time.Duration
+	conn      *telnet.Conn
+	done      chan struct{} // closed when conn is closed, stops its keepalive
+	lastUsed  time.Time
+}
+
+// NewClient creates a client for the console at addr. Without a username, the console must
+// be on localhost, where world assumes admin
+func NewClient(addr string, username string, password string) *Client {
+	return &Client{
+		addr:      addr,
+		username:  username,
+		password:  password,
+		Keepalive: DefaultKeepalive,
+	}
+}
+
+// NewClientFromConfig creates a client for the console described by world's telnet section
+func NewClientFromConfig(cfg config.TelnetConfig, username string, password string) (*Client, error) {
+	if strings.EqualFold(cfg.Enabled, "false") || cfg.Enabled == "0" {
+		return nil, ErrDisabled
+	}
+	host := cfg.IP
+	// world listens on every interface, but is reached on localhost
+	if host == "" || host == "0.0.0.0" {
+		host = "127.0.0.1"
+	}
+	port := cfg.Port
+	if port == "" {
+		port = "9000"
+	}
+	return NewClient(net.JoinHostPort(host, port), username, password), nil
+}
+
+// Addr returns the address of the console
+func (c *Client) Addr() string {
+	return c.addr
+}
+
+// Command sends a console command and returns its output. A dropped connection is
+// reconnected and the command retried once
+func (c *Client) Command(ctx context.Context, cmd string) (string, error) {
+	if c == nil {
+		return "", ErrNoClient
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	isFresh := c.conn == nil
+	if isFresh {
+		err := c.connect(ctx)
+		if err != nil {
+			return "", err
+		}
+	}
+	out, err := c.command(ctx, cmd)
+	if err == nil || isFresh || ctx.Err() != nil {
+		return out, err
+	}
+	// world may have restarted since the connection was last used
+	flog.Printf("[telnet] %s: %s, reconnecting\n", cmd, err)
+	err = c.connect(ctx)
+	if err != nil {
+		return "", err
+	}
+	return c.command(ctx, cmd)
+}
+
+// API calls an api method, such as get_client_list, and decodes the data of its response into v
+func (c *Client) API(ctx context.Context, method string, v any) error {
+	resp, err := c.Command(ctx, "api "+method)
+	if err != nil {
+		return fmt.Errorf("api %s: %w", method, err)
+	}
+	apiResp := struct {
+		Data          json.RawMessage `json:"data"`
+		Error         string          `json:"error"`
+		ExecutionTime string          `json:"execution_time"`
+		Method        string          `json:"method"`
+	}{}
+	err = json.Unmarshal([]byte(resp), &apiResp)
+	if err != nil {
+		return fmt.Errorf("api %s unmarshal: %w", method, err)
+	}
+	if apiResp.Error != "" {
+		return fmt.Errorf("api %s: %s", method, apiResp.Error)
+	}
+	if v == nil || len(apiResp.Data) == 0 {
+		return nil
+	}
+	err = json.Unmarshal(apiResp.Data, v)
+	if err != nil {
+		return fmt.Errorf("api %s data: %w", method, err)
+	}
+	return nil
+}
+
+// Broadcast sends a message to every player in world
+func (c *Client) Broadcast(ctx context.Context, message string) error {
+	_, err := c.Command(ctx, "broadcast "+message)
+	return err
+}
+
+// Lock keeps players from logging in to world, until Unlock or world restarts
+func (c *Client) Lock(ctx context.Context) error {
+	_, err := c.Command(ctx, "lock")
+	return err
+}
+
+// Unlock lets players log in to world again
+func (c *Client) Unlock(ctx context.Context) error {
+	_, err := c.Command(ctx, "unlock")
+	return err
+}
+
+// Close closes the connection, the next request reconnects
+func (c *Client) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.close()
+}
+
+func (c *Client) close() error {
+	if c.conn == nil {
+		return nil
+	}
+	close(c.done)
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// connect dials and logs in to the console, replacing any existing connection
+func (c *Client) connect(ctx context.Context) error {
+	c.close()
+
+	dialer := net.Dialer{Timeout: DefaultTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	conn, err := telnet.NewConn(netConn)
+	if err != nil {
+		netConn.Close()
+		return fmt.Errorf("new conn: %w", err)
+	}
+	err = c.login(ctx, conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	c.conn = conn
+	c.done = make(chan struct{})
+	c.lastUsed = time.Now()
+	if c.Keepalive > 0 {
+		go c.keepalive(conn, c.done)
+	}
+
+	for _, cmd := range []string{"echo off", "acceptmessages off"} {
+		_, err = c.command(ctx, cmd)
+		if err != nil {
+			c.close()
+			return fmt.Errorf("%s: %w", cmd, err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) login(ctx context.Context, conn *telnet.Conn) error {
+	stop := deadline(ctx, conn)
+	defer stop()
+
+	index, err := conn.SkipUntilIndex("Username:", "Connection established from localhost, assuming admin")
+	if err != nil {
+		return fmt.Errorf("unexpected initial handshake: %w", err)
+	}
+	if index == 1 {
+		// world sends a prompt once logged in
+		err = conn.SkipUntil("> ")
+		if err != nil {
+			return fmt.Errorf("prompt: %w", err)
+		}
+		return nil
+	}
+	if c.username == "" {
+		return fmt.Errorf("console asked for a login, but no telnet username is set")
+	}
+
+	err = sendln(conn, c.username)
+	if err != nil {
+		return fmt.Errorf("username: %w", err)
+	}
+	err = conn.SkipUntil("Password:")
+	if err != nil {
+		return fmt.Errorf("password prompt: %w", err)
+	}
+	err = sendln(conn, c.password)
+	if err != nil {
+		return fmt.Errorf("password: %w", err)
+	}
+	index, err = conn.SkipUntilIndex("> ", "Login failed")
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	if index == 1 {
+		return fmt.Errorf("login failed for %s", c.username)
+	}
+	return nil
+}
+
+// command sends a command on the current connection and reads until the prompt
+func (c *Client) command(ctx context.Context, cmd string) (string, error) {
+	stop := deadline(ctx, c.conn)
+	defer stop()
+	c.lastUsed = time.Now()
+
+	err := sendln(c.conn, cmd)
+	if err != nil {
+		c.close()
+		return "", err
+	}
+	data, err := c.conn.ReadUntil(linebreak)
+	if err != nil {
+		c.close()
+		return "", fmt.Errorf("read until: %w", err)
+	}
+	return strings.Replace(string(data), linebreak, "", 1), nil
+}
+
+// keepalive checks an idle connection every Keepalive, closing it if world stopped answering
+func (c *Client) keepalive(conn *telnet.Conn, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(c.Keepalive):
+		}
+		c.mu.Lock()
+		if c.conn != conn {
+			c.mu.Unlock()
+			return
+		}
+		if time.Since(c.lastUsed) >= c.Keepalive {
+			_, err := c.command(context.Background(), "echo off")
+			if err != nil {
+				flog.Printf("[telnet] keepalive: %s\n", err)
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+// deadline applies ctx's deadline, or DefaultTimeout, to conn, and interrupts it if ctx is
+// cancelled. The returned func must be called once the request is done
+func deadline(ctx context.Context, conn *telnet.Conn) func() {
+	at, ok := ctx.Deadline()
+	if !ok {
+		at = time.Now().Add(DefaultTimeout)
+	}
+	conn.SetDeadline(at)
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	return func() { stop() }
+}
+
+// sendln sends a line to the telnet server
+func sendln(conn *telnet.Conn, s string) error {
+	buf := make([]byte, len(s)+1)
+	copy(buf, s)
+	buf[len(s)] = '\n'
+
+	_, err := conn.Write(buf)
+	if err != nil {
+		return fmt.Errorf("sendln: %s: %w", s, err)
+	}
+	return nil
+}
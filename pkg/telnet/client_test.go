@@ -0,0 +1,173 @@
+package telnet
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xackery/overseer/pkg/reporter"
+	"github.com/xackery/overseer/pkg/telnet/telnettest"
+)
+
+func newServer(t *testing.T) *telnettest.Server {
+	s, err := telnettest.NewServer()
+	if err != nil {
+		t.Fatalf("new server: %s", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	err = s.SetAPI("get_client_list", []client{
+		{Name: "Xackery", Level: 60, Class: 2, Zone: 202, Online: 1},
+		{Name: "Shin", Level: 30, Class: 12, Zone: 202, Online: 1},
+	})
+	if err != nil {
+		t.Fatalf("set api: %s", err)
+	}
+	err = s.SetAPI("get_zone_list", []zoneServer{{ZoneID: 202, ZoneName: "poknowledge", ZoneOSPID: 4321}})
+	if err != nil {
+		t.Fatalf("set api: %s", err)
+	}
+	return s
+}
+
+func TestClientAPI(t *testing.T) {
+	s := newServer(t)
+	s.SetCommand("uptime 0", "Worldserver Uptime: 0 Days, 1 Hours, 2 Minutes, 3 Seconds")
+	c := NewClient(s.Addr(), "", "")
+	defer c.Close()
+	ctx := context.Background()
+
+	clients := []client{}
+	err := c.API(ctx, "get_client_list", &clients)
+	if err != nil {
+		t.Fatalf("api: %s", err)
+	}
+	if len(clients) != 2 || clients[0].Name != "Xackery" {
+		t.Fatalf("unexpected clients: %+v", clients)
+	}
+
+	out, err := c.Command(ctx, "uptime 0")
+	if err != nil {
+		t.Fatalf("command: %s", err)
+	}
+	if !strings.HasPrefix(out, "Worldserver Uptime") {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	err = c.API(ctx, "get_unknown", nil)
+	if err == nil {
+		t.Fatalf("expected unknown method error")
+	}
+
+	received := s.Received()
+	if len(received) < 2 || received[0] != "echo off" || received[1] != "acceptmessages off" {
+		t.Fatalf("expected echo and messages turned off first, got %v", received)
+	}
+	if s.Logins() != 1 {
+		t.Fatalf("expected one connection to be reused, got %d logins", s.Logins())
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	s := newServer(t)
+	c := NewClient(s.Addr(), "", "")
+	defer c.Close()
+	ctx := context.Background()
+
+	err := c.API(ctx, "get_client_list", nil)
+	if err != nil {
+		t.Fatalf("api: %s", err)
+	}
+	s.DropConnections()
+	err = c.API(ctx, "get_client_list", nil)
+	if err != nil {
+		t.Fatalf("api after drop: %s", err)
+	}
+	if s.Logins() != 2 {
+		t.Fatalf("expected a reconnect, got %d logins", s.Logins())
+	}
+}
+
+func TestClientLogin(t *testing.T) {
+	s := newServer(t)
+	s.SetLogin("admin", "secret")
+	ctx := context.Background()
+
+	c := NewClient(s.Addr(), "admin", "wrong")
+	_, err := c.Command(ctx, "echo off")
+	if err == nil || !strings.Contains(err.Error(), "login failed") {
+		t.Fatalf("expected login failure, got %v", err)
+	}
+
+	c = NewClient(s.Addr(), "admin", "secret")
+	defer c.Close()
+	_, err = c.Command(ctx, "echo off")
+	if err != nil {
+		t.Fatalf("command: %s", err)
+	}
+}
+
+func TestNilClient(t *testing.T) {
+	SetClient(nil)
+	if IsEnabled() {
+		t.Fatalf("expected no world console")
+	}
+	err := Ping(context.Background())
+	if !errors.Is(err, ErrNoClient) {
+		t.Fatalf("expected ErrNoClient, got %v", err)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	s := newServer(t)
+	oldInterval := Interval
+	Interval = 10 * time.Millisecond
+	SetClient(NewClient(s.Addr(), "", ""))
+	defer func() {
+		Interval = oldInterval
+		SetClient(nil)
+	}()
+	reporter.SetAppState("world", reporter.AppStateRunning)
+	reporter.SetAppPID("world", 1234)
+	defer reporter.RemoveApp("world")
+	reporter.SetAppPID("zone3", 4321)
+	defer reporter.RemoveApp("zone3")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		collect(ctx, "world")
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for reporter.World().Online != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 online, got %+v", reporter.World())
+		}
+		for len(reporter.SendUpdateChan) > 0 {
+			<-reporter.SendUpdateChan
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stats := reporter.World()
+	if stats.AvgLevel != 45 || stats.PopularClass != "Cleric" {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	populations := reporter.ZonePopulations()
+	if len(populations) != 1 || populations[0].App != "zone3" || populations[0].Players != 2 || populations[0].Zones != "poknowledge" {
+		t.Fatalf("unexpected zone populations: %+v", populations)
+	}
+
+	// an erroring world is still up, and keeps its stats
+	reporter.SetAppState("world", reporter.AppStateErroring)
+	time.Sleep(50 * time.Millisecond)
+	if reporter.World().Online != 2 {
+		t.Fatalf("expected stats kept while world is erroring, got %+v", reporter.World())
+	}
+}
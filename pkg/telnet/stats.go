@@ -0,0 +1,121 @@
+package telnet
+
+import (
+	"context"
+	"time"
+
+	"github.com/xackery/overseer/pkg/flog"
+	"github.com/xackery/overseer/pkg/reporter"
+	"github.com/xackery/overseer/pkg/signal"
+)
+
+var (
+	// Interval is how often world's client list is polled
+	Interval = 15 * time.Second
+	// MaxBackoff caps the wait between attempts while world's console is not answering
+	MaxBackoff = 5 * time.Minute
+)
+
+// New starts collecting world stats once world is running, until signal is cancelled. worldApp
+// is the name world is managed as. Nothing is collected without a world console
+func New(worldApp string) {
+	if !IsEnabled() {
+		flog.Printf("[telnet] no world console, not collecting stats\n")
+		return
+	}
+	signal.AddWorker()
+	go func() {
+		defer signal.FinishWorker()
+		collect(signal.Ctx(), worldApp)
+	}()
+}
+
+func collect(ctx context.Context, worldApp string) {
+	failures := 0
+	delay := Interval
+	for {
+		select {
+		case <-ctx.Done():
+			WorldClient().Close()
+			return
+		case <-time.After(delay):
+		}
+
+		state, _ := reporter.State(worldApp)
+		if !isWorldUp(worldApp, state) {
+			// the console goes away with world, so reconnect fresh once it is back
+			WorldClient().Close()
+			if reporter.World().Online > 0 {
+				reporter.SetWorldStats(reporter.WorldStats{PopularClass: "None", At: time.Now()})
+			}
+			failures = 0
+			delay = Interval
+			continue
+		}
+		if state == reporter.AppStateStarting {
+			// the console is not listening yet, the last stats are kept meanwhile
+			continue
+		}
+
+		clients := []client{}
+		reqCtx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+		err := WorldClient().API(reqCtx, "get_client_list", &clients)
+		cancel()
+		if err != nil {
+			failures++
+			delay = backoff(failures)
+			flog.Printf("[telnet] stats: %s, retrying in %s\n", err, delay)
+			continue
+		}
+		if failures > 0 {
+			flog.Printf("[telnet] stats recovered after %d failures\n", failures)
+		}
+		failures = 0
+		delay = Interval
+
+		// players are still listed without their zone if the zone list fails
+		zones := []zoneServer{}
+		reqCtx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		err = WorldClient().API(reqCtx, "get_zone_list", &zones)
+		cancel()
+		if err != nil {
+			flog.Printf("[telnet] stats: %s\n", err)
+		}
+		stats := aggregate(clients)
+		stats.Players = players(clients, zones, appPIDs())
+		reporter.SetWorldStats(stats)
+	}
+}
+
+// isWorldUp returns true if world has a process, even if it is erroring or hung
+func isWorldUp(worldApp string, state reporter.AppState) bool {
+	if state == reporter.AppStateUnknown || state == reporter.AppStateStopped {
+		return false
+	}
+	report, ok := reporter.Report(worldApp)
+	return ok && (report.PID != 0 || report.ID != "")
+}
+
+// appPIDs maps the pid of every running app to its name
+func appPIDs() map[int]string {
+	apps := make(map[int]string)
+	for _, report := range reporter.Reports() {
+		if report.PID == 0 {
+			continue
+		}
+		apps[report.PID] = report.Name
+	}
+	return apps
+}
+
+// backoff doubles Interval for each failure in a row, up to MaxBackoff
+func backoff(failures int) time.Duration {
+	delay := Interval
+	for i := 0; i < failures && delay < MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > MaxBackoff {
+		delay = MaxBackoff
+	}
+	return delay
+}
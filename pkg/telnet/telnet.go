@@ -1,29 +1,26 @@
 package telnet
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"strings"
+	"sort"
 	"sync"
 	"time"
 
-	"github.com/xackery/overseer/pkg/flog"
-	"github.com/ziutek/telnet"
-)
-
-var (
-	mu            sync.Mutex
-	onlineCount   int
-	avgLevel      int
-	popularClass  string
-	nextRefresh   time.Time
-	isInitialized bool
+	"github.com/xackery/overseer/pkg/reporter"
 )
 
 const (
 	linebreak = "\n\r> "
 )
 
+var (
+	mu sync.RWMutex
+	// world is the client for world's console, shared by Ping and the stats collector, nil
+	// until eqemu_config.json says where the console is
+	world *Client
+)
+
 var classNames = map[int]string{
 	1:  "Warrior",
 	2:  "Cleric",
@@ -43,236 +40,151 @@ var classNames = map[int]string{
 	16: "Berserker",
 }
 
-// OnlineCount returns the number of online users.
-func OnlineCount() int {
-	mu.Lock()
-	defer mu.Unlock()
-	return onlineCount
-	// if !isInitialized {
-	// 	isInitialized = true
-	// 	nextRefresh = time.Now().Add(3 * time.Second)
-	// }
-	// if time.Now().Before(nextRefresh) {
-	// 	return onlineCount
-	// }
-	// nextRefresh = time.Now().Add(1 * time.Minute)
-	// refreshStats()
-	// return onlineCount
+// client is an entry of api get_client_list
+type client struct {
+	AccountID            int    `json:"account_id"`
+	AccountName          string `json:"account_name"`
+	Admin                int    `json:"admin"`
+	Anon                 int    `json:"anon"`
+	CharacterID          int    `json:"character_id"`
+	Class                int    `json:"class"`
+	ClientVersion        int    `json:"client_version"`
+	Gm                   int    `json:"gm"`
+	GuildID              int64  `json:"guild_id"`
+	GuildRank            int    `json:"guild_rank"`
+	GuildTributeOptIn    bool   `json:"guild_tribute_opt_in"`
+	ID                   int    `json:"id"`
+	Instance             int    `json:"instance"`
+	IP                   int    `json:"ip"`
+	IsLocalClient        bool   `json:"is_local_client"`
+	Level                int    `json:"level"`
+	Lfg                  bool   `json:"lfg"`
+	LfgComments          string `json:"lfg_comments"`
+	LfgFromLevel         int    `json:"lfg_from_level"`
+	LfgMatchFilter       bool   `json:"lfg_match_filter"`
+	LfgToLevel           int    `json:"lfg_to_level"`
+	LoginserverAccountID int    `json:"loginserver_account_id"`
+	LoginserverID        int    `json:"loginserver_id"`
+	LoginserverName      string `json:"loginserver_name"`
+	Name                 string `json:"name"`
+	Online               int    `json:"online"`
+	Race                 int    `json:"race"`
+	Server               any    `json:"server"`
+	TellsOff             int    `json:"tells_off"`
+	WorldAdmin           int    `json:"world_admin"`
+	Zone                 int    `json:"zone"`
 }
 
-func AvgLevel() int {
-	mu.Lock()
-	defer mu.Unlock()
-	return avgLevel
-	// if !isInitialized {
-	// 	isInitialized = true
-	// 	nextRefresh = time.Now().Add(3 * time.Second)
-	// }
-	// if time.Now().Before(nextRefresh) {
-	// 	return avgLevel
-	// }
-	// nextRefresh = time.Now().Add(1 * time.Minute)
-	// refreshStats()
-	// return avgLevel
+// zoneServer is an entry of api get_zone_list, a zone process connected to world
+type zoneServer struct {
+	ID            int    `json:"id"`
+	ZoneID        int    `json:"zone_id"`
+	InstanceID    int    `json:"instance_id"`
+	ZoneName      string `json:"zone_name"`
+	ZoneLongName  string `json:"zone_long_name"`
+	ZoneOSPID     int    `json:"zone_os_pid"`
+	NumberPlayers int    `json:"number_players"`
+	IsStaticZone  bool   `json:"is_static_zone"`
+	LaunchName    string `json:"launch_name"`
 }
 
-func PopularClass() string {
-	mu.Lock()
-	defer mu.Unlock()
-	return popularClass
-	// if !isInitialized {
-	// 	isInitialized = true
-	// 	nextRefresh = time.Now().Add(3 * time.Second)
-	// }
-	// if time.Now().Before(nextRefresh) {
-	// 	return popularClass
-	// }
-	// nextRefresh = time.Now().Add(1 * time.Minute)
-	// refreshStats()
-	// return popularClass
-}
-
-func refreshStats() {
-	flog.Printf("[telnet] refreshing stats\n")
-	conn, err := connect()
-	if err != nil {
-		flog.Printf("[telnet] connect: %s\n", err)
-		return
-	}
-
-	onlineCount = 0
-
-	type apiRespStruct struct {
-		Data []struct {
-			AccountID            int    `json:"account_id"`
-			AccountName          string `json:"account_name"`
-			Admin                int    `json:"admin"`
-			Anon                 int    `json:"anon"`
-			CharacterID          int    `json:"character_id"`
-			Class                int    `json:"class"`
-			ClientVersion        int    `json:"client_version"`
-			Gm                   int    `json:"gm"`
-			GuildID              int64  `json:"guild_id"`
-			GuildRank            int    `json:"guild_rank"`
-			GuildTributeOptIn    bool   `json:"guild_tribute_opt_in"`
-			ID                   int    `json:"id"`
-			Instance             int    `json:"instance"`
-			IP                   int    `json:"ip"`
-			IsLocalClient        bool   `json:"is_local_client"`
-			Level                int    `json:"level"`
-			Lfg                  bool   `json:"lfg"`
-			LfgComments          string `json:"lfg_comments"`
-			LfgFromLevel         int    `json:"lfg_from_level"`
-			LfgMatchFilter       bool   `json:"lfg_match_filter"`
-			LfgToLevel           int    `json:"lfg_to_level"`
-			LoginserverAccountID int    `json:"loginserver_account_id"`
-			LoginserverID        int    `json:"loginserver_id"`
-			LoginserverName      string `json:"loginserver_name"`
-			Name                 string `json:"name"`
-			Online               int    `json:"online"`
-			Race                 int    `json:"race"`
-			Server               any    `json:"server"`
-			TellsOff             int    `json:"tells_off"`
-			WorldAdmin           int    `json:"world_admin"`
-			Zone                 int    `json:"zone"`
-		} `json:"data"`
-		ExecutionTime string `json:"execution_time"`
-		Method        string `json:"method"`
-	}
-	apiResp := &apiRespStruct{}
-	resp, err := command(conn, "api get_client_list")
-	if err != nil {
-		flog.Printf("[telnet] api get_client_list: %s\n", err)
-		return
-	}
-
-	flog.Printf("resp: %s\n", resp)
-	err = json.Unmarshal([]byte(resp), &apiResp)
-	if err != nil {
-		flog.Printf("[telnet] get_client_list unmarshal: %s\n", err)
-		return
-	}
-
-	flog.Printf("[telnet] api get_client_list: %s\n", resp)
-
-	avgLevel := 0
-	classes := make(map[int]int)
-
-	for _, client := range apiResp.Data {
-		if client.Online == 0 {
+// players returns the online clients, with the zone they are in and the app hosting it.
+// Zone processes are matched to apps by pid, apps maps pid to app name
+func players(clients []client, zones []zoneServer, apps map[int]string) []reporter.Player {
+	result := []reporter.Player{}
+	for _, c := range clients {
+		if c.Online == 0 {
 			continue
 		}
-		avgLevel += client.Level
-		classes[client.Class]++
-	}
-
-	popularClass = "None"
-	popularClassCount := 0
-	for class, count := range classes {
-		if count == 0 {
-			continue
+		player := reporter.Player{
+			Name:          c.Name,
+			Level:         c.Level,
+			Class:         className(c.Class),
+			Race:          c.Race,
+			ZoneID:        c.Zone,
+			InstanceID:    c.Instance,
+			Zone:          fmt.Sprintf("zone %d", c.Zone),
+			GuildID:       c.GuildID,
+			IsLFG:         c.Lfg,
+			IsGM:          c.Gm > 0,
+			ClientVersion: c.ClientVersion,
 		}
-		if count > popularClassCount {
-			popularClassCount = count
-			popularClass = classNames[class]
+		for _, zone := range zones {
+			if zone.ZoneID != c.Zone || zone.InstanceID != c.Instance {
+				continue
+			}
+			player.Zone = zone.ZoneName
+			player.App = apps[zone.ZoneOSPID]
+			break
 		}
+		result = append(result, player)
 	}
-
-	onlineCount = len(apiResp.Data)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
 }
 
-func connect() (*telnet.Conn, error) {
-	var err error
-	conn, err := telnet.Dial("tcp", "127.0.0.1:9000")
-	if err != nil {
-		return nil, fmt.Errorf("dial: %w", err)
-	}
-
-	err = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
-	if err != nil {
-		return nil, fmt.Errorf("set read deadline: %w", err)
+func className(class int) string {
+	name, ok := classNames[class]
+	if !ok {
+		return fmt.Sprintf("Class %d", class)
 	}
-	err = conn.SetWriteDeadline(time.Now().Add(2 * time.Second))
-	if err != nil {
-		return nil, fmt.Errorf("set write deadline: %w", err)
-	}
-	index := 0
+	return name
+}
 
-	index, err = conn.SkipUntilIndex("Username:", "Connection established from localhost, assuming admin")
-	if err != nil {
-		return nil, fmt.Errorf("unexpected initial handshake: %w", err)
-	}
-	if index == 0 {
-		return nil, fmt.Errorf("local bypass auth failed")
+// aggregate returns the stats of the online clients, ties for popular class go to the lowest class id
+func aggregate(clients []client) reporter.WorldStats {
+	stats := reporter.WorldStats{PopularClass: "None", At: time.Now()}
+	totalLevel := 0
+	classes := make(map[int]int)
+	for _, c := range clients {
+		if c.Online == 0 {
+			continue
+		}
+		stats.Online++
+		totalLevel += c.Level
+		classes[c.Class]++
 	}
-
-	err = sendln(conn, "echo off")
-	if err != nil {
-		return nil, fmt.Errorf("echo off: %w", err)
+	if stats.Online == 0 {
+		return stats
 	}
+	stats.AvgLevel = totalLevel / stats.Online
 
-	err = sendln(conn, "acceptmessages off")
-	if err != nil {
-		return nil, fmt.Errorf("acceptmessages off: %w", err)
+	popularClass := 0
+	for class, count := range classes {
+		if count > classes[popularClass] || (count == classes[popularClass] && class < popularClass) {
+			popularClass = class
+		}
 	}
-
-	return conn, nil
+	stats.PopularClass = className(popularClass)
+	return stats
 }
 
-// sendln sends a line to the telnet server.
-func sendln(conn *telnet.Conn, s string) error {
-	buf := make([]byte, len(s)+1)
-	copy(buf, s)
-	buf[len(s)] = '\n'
-
-	flog.Printf("[telnet] sendln: %s\n", s)
-
-	_, err := conn.Write(buf)
-	if err != nil {
-		return fmt.Errorf("sendLn: %s: %w", s, err)
-	}
-	return nil
+// SetClient sets the client Ping and the stats collector use, nil when world has no usable console
+func SetClient(c *Client) {
+	mu.Lock()
+	old := world
+	world = c
+	mu.Unlock()
+	old.Close()
 }
 
-// command sends a command to the telnet server and returns the output
-func command(conn *telnet.Conn, cmd string) (string, error) {
-	var err error
-
-	err = conn.SetReadDeadline(time.Now().Add(1 * time.Second))
-	if err != nil {
-		return "", fmt.Errorf("set read deadline: %w", err)
-	}
-	err = conn.SetWriteDeadline(time.Now().Add(1 * time.Second))
-	if err != nil {
-		return "", fmt.Errorf("set write deadline: %w", err)
-	}
-
-	err = sendln(conn, cmd)
-	if err != nil {
-		return "", fmt.Errorf("sendln: %w", err)
-	}
-
-	defer func() {
-		if r := recover(); r != nil {
-			flog.Printf("[telnet] panic in command: %s\n", r)
-		}
-	}()
-
-	var data []byte
-	var output string
-	for {
-		data, err = conn.ReadUntil(linebreak)
-		if err != nil {
-			return "", fmt.Errorf("read until: %w", err)
-		}
-
-		flog.Printf("[telnet] data: %s\n", string(data))
+// WorldClient returns the client for world's console, so other features can send it commands.
+// It is nil if there is none, and its requests return ErrNoClient
+func WorldClient() *Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return world
+}
 
-		output += string(data)
+// IsEnabled returns true if world has a telnet console to use
+func IsEnabled() bool {
+	return WorldClient() != nil
+}
 
-		if strings.Contains(output, linebreak) {
-			output = strings.Replace(output, linebreak, "", 1)
-			return output, nil
-		}
-	}
+// Ping sends a command to world's telnet console, returning an error if it does not answer
+func Ping(ctx context.Context) error {
+	_, err := WorldClient().Command(ctx, "echo off")
+	return err
 }
@@ -0,0 +1,74 @@
+package telnet
+
+import (
+	"testing"
+
+	"github.com/xackery/overseer/pkg/config"
+)
+
+func TestAggregate(t *testing.T) {
+	stats := aggregate([]client{
+		{Name: "Xackery", Level: 60, Class: 2, Online: 1},
+		{Name: "Shin", Level: 30, Class: 12, Online: 1},
+		{Name: "Rime", Level: 15, Class: 12, Online: 1},
+		{Name: "Gone", Level: 65, Class: 1, Online: 0},
+	})
+	if stats.Online != 3 || stats.AvgLevel != 35 || stats.PopularClass != "Wizard" {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	stats = aggregate(nil)
+	if stats.Online != 0 || stats.AvgLevel != 0 || stats.PopularClass != "None" {
+		t.Fatalf("unexpected empty stats: %+v", stats)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if backoff(1) != 2*Interval || backoff(2) != 4*Interval {
+		t.Fatalf("expected doubling, got %s and %s", backoff(1), backoff(2))
+	}
+	if backoff(100) != MaxBackoff {
+		t.Fatalf("expected %s cap, got %s", MaxBackoff, backoff(100))
+	}
+}
+
+func TestNewClientFromConfig(t *testing.T) {
+	c, err := NewClientFromConfig(config.TelnetConfig{IP: "0.0.0.0", Port: "9001", Enabled: "true"}, "", "")
+	if err != nil {
+		t.Fatalf("new client: %s", err)
+	}
+	if c.Addr() != "127.0.0.1:9001" {
+		t.Fatalf("expected 127.0.0.1:9001, got %s", c.Addr())
+	}
+	_, err = NewClientFromConfig(config.TelnetConfig{Enabled: "false"}, "", "")
+	if err != ErrDisabled {
+		t.Fatalf("expected disabled, got %v", err)
+	}
+}
+
+func TestPlayers(t *testing.T) {
+	result := players([]client{
+		{Name: "Xackery", Level: 60, Class: 2, Zone: 202, Online: 1},
+		{Name: "Shin", Level: 30, Class: 12, Zone: 202, Instance: 7, Online: 1},
+		{Name: "Rime", Level: 15, Class: 99, Zone: 1, Online: 1},
+		{Name: "Gone", Level: 65, Class: 1, Zone: 202, Online: 0},
+	}, []zoneServer{
+		{ZoneID: 202, ZoneName: "poknowledge", ZoneOSPID: 100},
+		{ZoneID: 202, InstanceID: 7, ZoneName: "poknowledge", ZoneOSPID: 200},
+	}, map[int]string{100: "poknowledge", 200: "zone3"})
+
+	if len(result) != 3 {
+		t.Fatalf("expected 3 online players, got %+v", result)
+	}
+	// sorted by name
+	rime, shin, xackery := result[0], result[1], result[2]
+	if xackery.Zone != "poknowledge" || xackery.App != "poknowledge" || xackery.Class != "Cleric" {
+		t.Fatalf("unexpected player: %+v", xackery)
+	}
+	if shin.App != "zone3" || shin.InstanceID != 7 {
+		t.Fatalf("expected instance to map to zone3, got %+v", shin)
+	}
+	if rime.Zone != "zone 1" || rime.App != "" || rime.Class != "Class 99" {
+		t.Fatalf("expected unknown zone and class, got %+v", rime)
+	}
+}
@@ -0,0 +1,208 @@
+// Package telnettest is a fake world telnet console for tests, answering the handshake,
+// echo, acceptmessages and api commands the way world does
+package telnettest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+const (
+	// prompt is sent after the handshake and after every command
+	prompt = "\n\r> "
+)
+
+// Server is a fake world telnet console listening on localhost
+type Server struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	username string
+	password string
+	apis     map[string]json.RawMessage
+	commands map[string]string
+	received []string
+	conns    map[net.Conn]bool
+	logins   int
+}
+
+// NewServer starts a fake console on a random localhost port, close it with Close
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+	s := &Server{
+		listener: listener,
+		apis:     make(map[string]json.RawMessage),
+		commands: make(map[string]string),
+		conns:    make(map[net.Conn]bool),
+	}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// Addr returns the host:port the console listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// SetLogin makes the console ask for a username and password, instead of assuming admin
+func (s *Server) SetLogin(username string, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.username = username
+	s.password = password
+}
+
+// SetAPI sets the data api method answers with, encoded as json
+func (s *Server) SetAPI(method string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", method, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apis[method] = raw
+	return nil
+}
+
+// SetCommand sets the output of a console command
+func (s *Server) SetCommand(cmd string, output string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands[cmd] = output
+}
+
+// Received returns every command sent to the console, in order
+func (s *Server) Received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.received...)
+}
+
+// Logins returns how many connections made it past the handshake
+func (s *Server) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// DropConnections closes every open connection, like world restarting
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// Close stops listening and closes every connection
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.DropConnections()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serve(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	s.mu.Lock()
+	username, password := s.username, s.password
+	s.mu.Unlock()
+
+	if username == "" {
+		fmt.Fprintf(conn, "Connection established from localhost, assuming admin\r\n%s", prompt)
+	} else {
+		fmt.Fprint(conn, "Username: ")
+		user, err := readLine(r)
+		if err != nil {
+			return
+		}
+		fmt.Fprint(conn, "Password: ")
+		pass, err := readLine(r)
+		if err != nil {
+			return
+		}
+		if user != username || pass != password {
+			fmt.Fprint(conn, "Login failed.\r\n")
+			return
+		}
+		fmt.Fprintf(conn, "Login accepted.\r\n%s", prompt)
+	}
+	s.mu.Lock()
+	s.logins++
+	s.mu.Unlock()
+
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return
+		}
+		_, err = fmt.Fprintf(conn, "%s%s", s.output(line), prompt)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// output returns what world would answer to a command
+func (s *Server) output(cmd string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.received = append(s.received, cmd)
+
+	switch cmd {
+	case "echo on", "echo off", "acceptmessages on", "acceptmessages off":
+		return ""
+	}
+	method, ok := strings.CutPrefix(cmd, "api ")
+	if ok {
+		data, ok := s.apis[method]
+		if !ok {
+			return fmt.Sprintf(`{"error":"Unknown method %s","execution_time":"0.0001","method":"%s"}`, method, method)
+		}
+		return fmt.Sprintf(`{"data":%s,"execution_time":"0.0001","method":"%s"}`, data, method)
+	}
+	output, ok := s.commands[cmd]
+	if !ok {
+		return "Command not found."
+	}
+	return output
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -12,10 +11,10 @@ import (
 
 	"github.com/erikgeiser/promptkit/confirmation"
 	"github.com/erikgeiser/promptkit/selection"
-	"github.com/shirou/gopsutil/v3/process"
 	"github.com/xackery/overseer/pkg/config"
 	"github.com/xackery/overseer/pkg/message"
 	"github.com/xackery/overseer/pkg/operation"
+	"github.com/xackery/overseer/pkg/pidfile"
 	"github.com/xackery/overseer/pkg/sanity"
 )
 
@@ -179,22 +178,14 @@ func run() error {
 	}*/
 
 	if !strings.Contains(choice, "zone") {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		processes, err := process.ProcessesWithContext(ctx)
+		// overseer records what it runs, so only a copy started by it is detected
+		running, err := pidfile.Leftovers(pidfile.DefaultPath)
 		if err != nil {
-			return fmt.Errorf("processes: %w", err)
+			return fmt.Errorf("pidfile: %w", err)
 		}
-		var n string
-		for _, p := range processes {
-			n, err = p.NameWithContext(ctx)
-			if err != nil {
-				continue
-			}
-			if !strings.Contains(n, choice) {
-				continue
-			}
-			isOK, err := confirmation.New(fmt.Sprintf("%s is already running. Start another copy?", n), confirmation.No).RunPrompt()
+		entry, ok := pidfile.Find(running, choice)
+		if ok {
+			isOK, err := confirmation.New(fmt.Sprintf("%s is already running as pid %d. Start another copy?", entry.Name, entry.PID), confirmation.No).RunPrompt()
 			if err != nil {
 				return fmt.Errorf("confirmation: %w", err)
 			}
@@ -202,7 +193,6 @@ func run() error {
 				message.OK("OK, exiting")
 				return nil
 			}
-			break
 		}
 	}
 